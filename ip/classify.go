@@ -0,0 +1,149 @@
+package ip
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/netip"
+)
+
+// Class is the kind of network an address belongs to.
+type Class int
+
+const (
+	ClassInvalid Class = iota
+	ClassUnspecified
+	ClassLoopback
+	ClassPrivate
+	ClassLinkLocal
+	ClassCGNAT
+	ClassDocumentation
+	ClassMulticast
+	ClassPublic
+	// ClassIPv4Mapped is only returned by ClassifyAddr, a net.IP does not keep the form of an address.
+	ClassIPv4Mapped
+)
+
+var classNames = []string{"invalid", "unspecified", "loopback", "private", "link-local", "cgnat", "documentation", "multicast", "public", "ipv4-mapped"}
+
+func (c Class) String() string {
+	if c < 0 || int(c) >= len(classNames) {
+		return classNames[ClassInvalid]
+	}
+	return classNames[c]
+}
+
+var (
+	// RFC 1918 and RFC 4193 unique local addresses
+	privateSet = MustNewSet("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7")
+	// RFC 6598 shared address space
+	cgnatSet = MustNewSet("100.64.0.0/10")
+	// RFC 5737 and RFC 3849
+	documentationSet = MustNewSet("192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24", "2001:db8::/32")
+)
+
+// Classify returns the class of ip, IPv4-mapped IPv6 addresses are classified as IPv4.
+func Classify(ip net.IP) Class {
+	switch {
+	case len(ip) != net.IPv4len && len(ip) != net.IPv6len:
+		return ClassInvalid
+	case ip.IsUnspecified():
+		return ClassUnspecified
+	case ip.IsLoopback():
+		return ClassLoopback
+	case IsPrivate(ip):
+		return ClassPrivate
+	case IsLinkLocal(ip):
+		return ClassLinkLocal
+	case IsCGNAT(ip):
+		return ClassCGNAT
+	case IsDocumentation(ip):
+		return ClassDocumentation
+	case ip.IsMulticast():
+		return ClassMulticast
+	}
+	return ClassPublic
+}
+
+// ClassifyAddr is like Classify but returns ClassIPv4Mapped for IPv4-mapped IPv6 addresses such as ::ffff:1.2.3.4.
+// Only a netip.Addr keeps the mapped form, as parsed from text like "::ffff:192.0.2.1".
+// A net.IP such as the result of GetIP cannot tell the forms apart, net.ParseIP stores every
+// IPv4 address in 16 bytes, so classify it with Classify instead.
+//
+//	addr := netip.MustParseAddr("::ffff:192.0.2.1")
+//	ip.ClassifyAddr(addr)         // ClassIPv4Mapped
+//	ip.ClassifyAddr(addr.Unmap()) // ClassDocumentation
+func ClassifyAddr(addr netip.Addr) Class {
+	if IsIPv4Mapped(addr) {
+		return ClassIPv4Mapped
+	}
+	if !addr.IsValid() {
+		return ClassInvalid
+	}
+	return Classify(net.IP(addr.AsSlice()))
+}
+
+// IsLoopback reports whether ip is a loopback address.
+func IsLoopback(ip net.IP) bool {
+	return ip.IsLoopback()
+}
+
+// IsPrivate reports whether ip is a RFC 1918 private or RFC 4193 unique local address.
+func IsPrivate(ip net.IP) bool {
+	return privateSet.Contains(ip)
+}
+
+// IsLinkLocal reports whether ip is a link-local unicast or multicast address.
+func IsLinkLocal(ip net.IP) bool {
+	return ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()
+}
+
+// IsCGNAT reports whether ip is in the 100.64.0.0/10 carrier-grade NAT range.
+func IsCGNAT(ip net.IP) bool {
+	return cgnatSet.Contains(ip)
+}
+
+// IsDocumentation reports whether ip is reserved for documentation.
+func IsDocumentation(ip net.IP) bool {
+	return documentationSet.Contains(ip)
+}
+
+// IsMulticast reports whether ip is a multicast address.
+func IsMulticast(ip net.IP) bool {
+	return ip.IsMulticast()
+}
+
+// IsIPv4Mapped reports whether addr is an IPv4 address written in the ::ffff:0:0/96 IPv6 form.
+// It takes a netip.Addr because net.ParseIP returns every IPv4 address in that form.
+func IsIPv4Mapped(addr netip.Addr) bool {
+	return addr.Is4In6()
+}
+
+// Anonymize truncates an IPv4 address to /24 and an IPv6 address to /48,
+// which is the usual form for storing addresses in logs.
+func Anonymize(ip net.IP) net.IP {
+	return Truncate(ip, 24, 48)
+}
+
+// Truncate zeroes all but the first v4Bits of an IPv4 address or v6Bits of an IPv6 address.
+func Truncate(ip net.IP, v4Bits, v6Bits int) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(v4Bits, 32))
+	}
+	if v6 := ip.To16(); v6 != nil {
+		return v6.Mask(net.CIDRMask(v6Bits, 128))
+	}
+	return nil
+}
+
+// Pseudonymize returns a stable keyed HMAC-SHA256 pseudonym of ip,
+// the same key always maps an address to the same value.
+func Pseudonymize(ip net.IP, key []byte) string {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(ip)
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,128 @@
+package ip
+
+import (
+	"net"
+	"strings"
+)
+
+// Forwarded is one element of a RFC 7239 Forwarded header.
+type Forwarded struct {
+	For   string
+	By    string
+	Host  string
+	Proto string
+}
+
+// ForIP returns the IP address of the "for" parameter,
+// nil when it is missing, obfuscated or "unknown".
+func (f *Forwarded) ForIP() net.IP {
+	return parseNode(f.For)
+}
+
+// ByIP returns the IP address of the "by" parameter.
+func (f *Forwarded) ByIP() net.IP {
+	return parseNode(f.By)
+}
+
+// ParseForwarded parses the value of a Forwarded header,
+// elements are returned in the order they are listed, closest to the client first.
+//
+//	Forwarded: for=192.0.2.43, for="[2001:db8:cafe::17]:4711";proto=https;by=203.0.113.60
+func ParseForwarded(value string) []Forwarded {
+	var ret []Forwarded
+	for _, element := range splitQuoted(value, ',') {
+		var f Forwarded
+		var ok bool
+		for _, pair := range splitQuoted(element, ';') {
+			eq := strings.IndexByte(pair, '=')
+			if eq == -1 {
+				continue
+			}
+			key := strings.ToLower(strings.TrimSpace(pair[:eq]))
+			val := unquote(strings.TrimSpace(pair[eq+1:]))
+			switch key {
+			case "for":
+				f.For = val
+			case "by":
+				f.By = val
+			case "host":
+				f.Host = val
+			case "proto":
+				f.Proto = strings.ToLower(val)
+			default:
+				continue
+			}
+			ok = true
+		}
+		if ok {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+// splitQuoted splits s by sep, ignoring separators inside quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	var quoted, escaped bool
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == sep:
+			if part := strings.TrimSpace(s[start:i]); part != "" {
+				parts = append(parts, part)
+			}
+			start = i + 1
+		}
+	}
+	if part := strings.TrimSpace(s[start:]); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	if strings.IndexByte(s, '\\') == -1 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// parseNode parses a node identifier such as "192.0.2.43", "192.0.2.43:80",
+// "[2001:db8::1]:4711" or "2001:db8::1".
+func parseNode(node string) net.IP {
+	if node == "" {
+		return nil
+	}
+	if node[0] == '[' {
+		if end := strings.IndexByte(node, ']'); end != -1 {
+			return net.ParseIP(node[1:end])
+		}
+		return nil
+	}
+	if ip := net.ParseIP(node); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return net.ParseIP(host)
+	}
+	return nil
+}
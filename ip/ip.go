@@ -6,23 +6,67 @@ import (
 	"strings"
 )
 
-var noProxy bool
+// Proxy headers understood by Resolver.
+const (
+	HeaderForwarded      = "Forwarded"
+	HeaderXForwardedFor  = "X-Forwarded-For"
+	HeaderXRealIP        = "X-Real-IP"
+	HeaderCFConnectingIP = "CF-Connecting-IP"
+	HeaderTrueClientIP   = "True-Client-IP"
+	HeaderXClientIP      = "X-Client-IP"
+)
+
+// DefaultHeaders is the header precedence used by GetIP.
+var DefaultHeaders = []string{
+	HeaderForwarded,
+	HeaderXForwardedFor,
+	HeaderXRealIP,
+	HeaderCFConnectingIP,
+	HeaderTrueClientIP,
+	HeaderXClientIP,
+}
+
+// Resolver resolves the client IP of a request from proxy headers.
+type Resolver struct {
+	// Headers lists the proxy headers to check, in order of precedence.
+	Headers []string
+	// NoProxy ignores all headers and only uses RemoteAddr.
+	NoProxy bool
+	// RewriteRemoteAddr makes Middleware replace r.RemoteAddr with the client IP.
+	RewriteRemoteAddr bool
+}
+
+// NewResolver returns a Resolver checking headers in the given order,
+// DefaultHeaders are used when none is given.
+func NewResolver(headers ...string) *Resolver {
+	if len(headers) == 0 {
+		headers = DefaultHeaders
+	}
+	return &Resolver{Headers: headers}
+}
+
+var defaultResolver = NewResolver()
 
 // GetIP returns IP address from request.
 // Only when it used use proxy
 func GetIP(r *http.Request) net.IP {
-	if !noProxy {
-		if ip := r.Header.Get("X-Forwarded-For"); ip != "" {
-			parts := strings.Split(ip, ",")
-			for i, part := range parts {
-				parts[i] = strings.TrimSpace(part)
+	return defaultResolver.GetIP(r)
+}
+
+// GetIP returns the client IP address of the request,
+// the first header in precedence order holding a valid address wins.
+func (rs *Resolver) GetIP(r *http.Request) net.IP {
+	if !rs.NoProxy {
+		for _, h := range rs.Headers {
+			if ip := headerIP(r.Header, h); ip != nil {
+				return ip
 			}
-			return net.ParseIP(parts[0])
-		}
-		if ip := r.Header.Get("X-Real-IP"); ip != "" {
-			return net.ParseIP(ip)
 		}
 	}
+	return remoteIP(r)
+}
+
+func remoteIP(r *http.Request) net.IP {
 	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
 		return net.ParseIP(r.RemoteAddr)
@@ -30,6 +74,32 @@ func GetIP(r *http.Request) net.IP {
 	return net.ParseIP(host)
 }
 
-func NoProxyMode()  {
-	noProxy = true
-}
\ No newline at end of file
+func headerIP(header http.Header, name string) net.IP {
+	value := header.Get(name)
+	if value == "" {
+		return nil
+	}
+	switch http.CanonicalHeaderKey(name) {
+	case HeaderForwarded:
+		for _, f := range ParseForwarded(value) {
+			if ip := f.ForIP(); ip != nil {
+				return ip
+			}
+		}
+		return nil
+	case http.CanonicalHeaderKey(HeaderXForwardedFor):
+		parts := strings.Split(value, ",")
+		return parseNode(strings.TrimSpace(parts[0]))
+	default:
+		return parseNode(strings.TrimSpace(value))
+	}
+}
+
+// SetHeaders changes the header precedence used by GetIP.
+func SetHeaders(headers ...string) {
+	defaultResolver.Headers = headers
+}
+
+func NoProxyMode() {
+	defaultResolver.NoProxy = true
+}
@@ -0,0 +1,212 @@
+package ip
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestParseForwarded(t *testing.T) {
+	fs := ParseForwarded(`for="[2001:db8::1]:4711";proto=HTTPS;host="example.com", for=192.0.2.43;by=unknown, for=_hidden`)
+	if len(fs) != 3 {
+		t.Fatal("expect 3 elements but got", len(fs))
+	}
+	if ip := fs[0].ForIP(); ip == nil || ip.String() != "2001:db8::1" {
+		t.Fatal("unexpected for", fs[0].For)
+	}
+	if fs[0].Proto != "https" || fs[0].Host != "example.com" {
+		t.Fatal("unexpected proto or host", fs[0])
+	}
+	if ip := fs[1].ForIP(); ip == nil || ip.String() != "192.0.2.43" {
+		t.Fatal("unexpected for", fs[1].For)
+	}
+	if fs[1].ByIP() != nil || fs[2].ForIP() != nil {
+		t.Fatal("obfuscated nodes should not resolve")
+	}
+}
+
+func TestGetIP(t *testing.T) {
+	cases := []struct {
+		header map[string]string
+		expect string
+	}{
+		{nil, "10.0.0.1"},
+		{map[string]string{"X-Forwarded-For": "192.0.2.1, 10.0.0.2"}, "192.0.2.1"},
+		{map[string]string{"X-Real-IP": "192.0.2.2"}, "192.0.2.2"},
+		{map[string]string{"CF-Connecting-IP": "2001:db8::2"}, "2001:db8::2"},
+		{map[string]string{"Forwarded": `for="[2001:db8::1]:4711"`, "X-Forwarded-For": "192.0.2.1"}, "2001:db8::1"},
+		{map[string]string{"Forwarded": "for=unknown", "X-Client-IP": "192.0.2.3"}, "192.0.2.3"},
+	}
+	for _, c := range cases {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		for k, v := range c.header {
+			r.Header.Set(k, v)
+		}
+		if ip := GetIP(r); ip.String() != c.expect {
+			t.Fatal(c.header, "resolved to", ip, "but expect", c.expect)
+		}
+	}
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "192.0.2.1")
+	r.Header.Set("True-Client-IP", "192.0.2.9")
+	if ip := NewResolver(HeaderTrueClientIP, HeaderXForwardedFor).GetIP(r); ip.String() != "192.0.2.9" {
+		t.Fatal("header precedence is not respected", ip)
+	}
+	if ip := (&Resolver{Headers: DefaultHeaders, NoProxy: true}).GetIP(r); ip.String() != "10.0.0.1" {
+		t.Fatal("no proxy mode should use remote addr", ip)
+	}
+}
+
+func TestSet(t *testing.T) {
+	s := MustNewSet("10.0.0.0/8", "192.168.1.1", "2001:db8::/32")
+	for _, c := range []struct {
+		ip     string
+		expect bool
+	}{
+		{"10.1.2.3", true},
+		{"11.0.0.1", false},
+		{"192.168.1.1", true},
+		{"192.168.1.2", false},
+		{"::ffff:10.0.0.1", true},
+		{"2001:db8:1::1", true},
+		{"2001:db9::1", false},
+	} {
+		if s.Contains(net.ParseIP(c.ip)) != c.expect {
+			t.Fatal(c.ip, "contains should be", c.expect)
+		}
+	}
+
+	s.Subtract(MustNewSet("10.1.0.0/16"))
+	if s.Contains(net.ParseIP("10.1.2.3")) || !s.Contains(net.ParseIP("10.2.0.1")) {
+		t.Fatal("subtract does not split ranges", s)
+	}
+	s.Merge(MustNewSet("10.1.0.0/16"))
+	if expect := "10.0.0.0/8,192.168.1.1/32,2001:db8::/32"; s.String() != expect {
+		t.Fatal("merge result is", s, "but expect", expect)
+	}
+	if err := s.AddNet(&net.IPNet{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.IPv4Mask(255, 0, 255, 0)}); err == nil || s.Contains(net.ParseIP("2001:db9::1")) {
+		t.Fatal("non-canonical masks should be rejected", s)
+	}
+	if s := MustNewSet("::/64"); !s.Contains(net.ParseIP("::ffff:10.0.0.1")) || !s.Contains(net.ParseIP("10.0.0.1")) || s.Contains(net.ParseIP("2001:db8::1")) {
+		t.Fatal("IPv6 networks covering IPv4-mapped addresses should match them", s)
+	}
+}
+
+func TestSetHandler(t *testing.T) {
+	s := MustNewSet("127.0.0.0/8")
+	handler := s.AllowHandler(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for addr, code := range map[string]int{"127.0.0.1:80": 200, "192.0.2.1:80": 403} {
+		r := httptest.NewRequest("GET", "/admin", nil)
+		r.RemoteAddr = addr
+		r.Header.Set(HeaderXClientIP, "127.0.0.1")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Fatal(addr, "got status", w.Code, "but expect", code)
+		}
+	}
+
+	handler = s.DenyHandler(&Resolver{Headers: []string{HeaderXRealIP}}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(HeaderXRealIP, "127.0.0.2")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != 403 {
+		t.Fatal("resolver headers are not used, got status", w.Code)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var info *RequestInfo
+	var remoteAddr string
+	handler := Middleware(&Resolver{Headers: DefaultHeaders, RewriteRemoteAddr: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info = InfoFromContext(r.Context())
+		remoteAddr = r.RemoteAddr
+	}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("Forwarded", `for=192.0.2.1;proto=https;host=example.com`)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if info == nil || info.IP.String() != "192.0.2.1" || info.BaseURL() != "https://example.com" {
+		t.Fatal("unexpected request info", info)
+	}
+	if remoteAddr != "192.0.2.1:1234" {
+		t.Fatal("remote addr is not rewritten", remoteAddr)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	for addr, class := range map[string]Class{
+		"127.0.0.1":          ClassLoopback,
+		"::1":                ClassLoopback,
+		"10.1.1.1":           ClassPrivate,
+		"::ffff:192.168.1.1": ClassPrivate,
+		"fd00::1":            ClassPrivate,
+		"169.254.1.1":        ClassLinkLocal,
+		"fe80::1":            ClassLinkLocal,
+		"100.64.0.1":         ClassCGNAT,
+		"198.51.100.7":       ClassDocumentation,
+		"2001:db8::1":        ClassDocumentation,
+		"239.1.2.3":          ClassMulticast,
+		"8.8.8.8":            ClassPublic,
+		"0.0.0.0":            ClassUnspecified,
+	} {
+		if c := Classify(net.ParseIP(addr)); c != class {
+			t.Fatal(addr, "is classified as", c, "but expect", class)
+		}
+	}
+	for addr, mapped := range map[string]bool{"::ffff:1.2.3.4": true, "1.2.3.4": false, "2001:db8::1": false} {
+		if IsIPv4Mapped(netip.MustParseAddr(addr)) != mapped {
+			t.Fatal("IsIPv4Mapped is wrong for", addr)
+		}
+	}
+	if c := ClassifyAddr(netip.MustParseAddr("::ffff:1.2.3.4")); c != ClassIPv4Mapped || c.String() != "ipv4-mapped" {
+		t.Fatal("unexpected class", c)
+	}
+	if c := ClassifyAddr(netip.MustParseAddr("::ffff:192.0.2.1").Unmap()); c != ClassDocumentation {
+		t.Fatal("unmapped address is classified as", c)
+	}
+	if c := ClassifyAddr(netip.MustParseAddr("10.1.1.1")); c != ClassPrivate {
+		t.Fatal("unexpected class", c)
+	}
+	if c := ClassifyAddr(netip.Addr{}); c != ClassInvalid {
+		t.Fatal("unexpected class", c)
+	}
+}
+
+func TestAnonymize(t *testing.T) {
+	if ip := Anonymize(net.ParseIP("192.0.2.123")); ip.String() != "192.0.2.0" {
+		t.Fatal("unexpected anonymized IPv4", ip)
+	}
+	if ip := Anonymize(net.ParseIP("2001:db8:1234:5678::1")); ip.String() != "2001:db8:1234::" {
+		t.Fatal("unexpected anonymized IPv6", ip)
+	}
+	a := Pseudonymize(net.ParseIP("192.0.2.1"), []byte("key"))
+	if a != Pseudonymize(net.ParseIP("::ffff:192.0.2.1"), []byte("key")) || a == Pseudonymize(net.ParseIP("192.0.2.1"), []byte("other")) {
+		t.Fatal("pseudonyms should be stable per key")
+	}
+}
+
+func TestSelectIP(t *testing.T) {
+	addrs := []net.IP{net.ParseIP("fe80::1"), net.ParseIP("2001:db8::1"), net.ParseIP("169.254.0.1").To4(), net.ParseIP("192.0.2.1").To4()}
+	for policy, expect := range map[Policy]string{
+		PreferIPv4: "192.0.2.1",
+		PreferIPv6: "2001:db8::1",
+		IPv6Only:   "2001:db8::1",
+	} {
+		if ip := selectIP(addrs, policy); ip.String() != expect {
+			t.Fatal("policy", policy, "selected", ip, "but expect", expect)
+		}
+	}
+	if ip := selectIP(addrs[:1], IPv4Only); ip != nil {
+		t.Fatal("IPv4Only should not select", ip)
+	}
+	if _, err := LocalAddrs(); err != nil {
+		t.Fatal(err)
+	}
+}
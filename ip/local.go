@@ -0,0 +1,118 @@
+package ip
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Policy decides which address family PreferredOutboundIP picks.
+type Policy int
+
+const (
+	PreferIPv4 Policy = iota
+	PreferIPv6
+	IPv4Only
+	IPv6Only
+)
+
+// ErrNoAddress is returned when no usable local address is found.
+var ErrNoAddress = errors.New("ip: no usable local address")
+
+// VirtualInterfacePrefixes lists name prefixes of interfaces ignored by LocalAddrs,
+// such as container bridges, VPN tunnels and hypervisor networks.
+var VirtualInterfacePrefixes = []string{
+	"docker", "veth", "br-", "virbr", "vmnet", "vboxnet", "cni", "flannel",
+	"cali", "kube", "tun", "tap", "utun", "awdl", "llw", "zt",
+}
+
+// LocalAddrs returns the unicast addresses of all interfaces that are up,
+// not loopback and not virtual, ordered by interface index.
+func LocalAddrs() ([]net.IP, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var ret []net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || isVirtual(iface.Name) {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		var ips []net.IP
+		for _, addr := range addrs {
+			var ip net.IP
+			switch v := addr.(type) {
+			case *net.IPNet:
+				ip = v.IP
+			case *net.IPAddr:
+				ip = v.IP
+			}
+			if ip == nil || ip.IsLoopback() || ip.IsUnspecified() || ip.IsMulticast() {
+				continue
+			}
+			if v4 := ip.To4(); v4 != nil {
+				ip = v4
+			}
+			ips = append(ips, ip)
+		}
+		sortIPs(ips)
+		ret = append(ret, ips...)
+	}
+	return ret, nil
+}
+
+// PreferredOutboundIP returns a stable address identifying this machine,
+// usable for service registration. Non link-local addresses are preferred.
+func PreferredOutboundIP(policy Policy) (net.IP, error) {
+	addrs, err := LocalAddrs()
+	if err != nil {
+		return nil, err
+	}
+	if ip := selectIP(addrs, policy); ip != nil {
+		return ip, nil
+	}
+	return nil, ErrNoAddress
+}
+
+func selectIP(addrs []net.IP, policy Policy) net.IP {
+	var best net.IP
+	bestScore := -1
+	for _, ip := range addrs {
+		v4 := ip.To4() != nil
+		if (policy == IPv4Only && !v4) || (policy == IPv6Only && v4) {
+			continue
+		}
+		score := 0
+		if !ip.IsLinkLocalUnicast() {
+			score += 2
+		}
+		if v4 == (policy != PreferIPv6) {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = ip, score
+		}
+	}
+	return best
+}
+
+func isVirtual(name string) bool {
+	for _, prefix := range VirtualInterfacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortIPs(ips []net.IP) {
+	sort.Slice(ips, func(i, j int) bool {
+		return bytes.Compare(ips[i].To16(), ips[j].To16()) < 0
+	})
+}
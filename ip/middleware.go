@@ -0,0 +1,127 @@
+package ip
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Headers describing the original request scheme and host.
+const (
+	HeaderXForwardedProto = "X-Forwarded-Proto"
+	HeaderXForwardedHost  = "X-Forwarded-Host"
+)
+
+// RequestInfo is the client information resolved once by Middleware.
+type RequestInfo struct {
+	IP     net.IP
+	Scheme string
+	Host   string
+}
+
+// BaseURL returns the absolute base URL the client used, like "https://example.com".
+func (i *RequestInfo) BaseURL() string {
+	return i.Scheme + "://" + i.Host
+}
+
+type infoKey struct{}
+
+// NewContext returns a copy of ctx carrying info.
+func NewContext(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+// InfoFromContext returns the RequestInfo stored by Middleware, nil if there is none.
+func InfoFromContext(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(infoKey{}).(*RequestInfo)
+	return info
+}
+
+// FromContext returns the client IP stored by Middleware, nil if there is none.
+func FromContext(ctx context.Context) net.IP {
+	if info := InfoFromContext(ctx); info != nil {
+		return info.IP
+	}
+	return nil
+}
+
+// Middleware resolves the client IP, scheme and host once per request
+// and stores them in the request context, see FromContext and InfoFromContext.
+// A nil resolver uses the same settings as GetIP.
+func Middleware(resolver *Resolver) func(http.Handler) http.Handler {
+	if resolver == nil {
+		resolver = defaultResolver
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := resolver.Resolve(r)
+			if resolver.RewriteRemoteAddr && info.IP != nil {
+				port := "0"
+				if _, p, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+					port = p
+				}
+				r.RemoteAddr = net.JoinHostPort(info.IP.String(), port)
+			}
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), info)))
+		})
+	}
+}
+
+// Resolve returns the client IP, scheme and host of the request.
+func (rs *Resolver) Resolve(r *http.Request) *RequestInfo {
+	return &RequestInfo{
+		IP:     rs.GetIP(r),
+		Scheme: rs.Scheme(r),
+		Host:   rs.Host(r),
+	}
+}
+
+// Scheme returns the scheme the client used, "http" or "https".
+func (rs *Resolver) Scheme(r *http.Request) string {
+	if !rs.NoProxy {
+		for _, f := range ParseForwarded(r.Header.Get(HeaderForwarded)) {
+			if f.Proto != "" {
+				return f.Proto
+			}
+		}
+		if proto := firstValue(r.Header.Get(HeaderXForwardedProto)); proto != "" {
+			return strings.ToLower(proto)
+		}
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host returns the host the client used.
+func (rs *Resolver) Host(r *http.Request) string {
+	if !rs.NoProxy {
+		for _, f := range ParseForwarded(r.Header.Get(HeaderForwarded)) {
+			if f.Host != "" {
+				return f.Host
+			}
+		}
+		if host := firstValue(r.Header.Get(HeaderXForwardedHost)); host != "" {
+			return host
+		}
+	}
+	return r.Host
+}
+
+func firstValue(value string) string {
+	if i := strings.IndexByte(value, ','); i != -1 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+// clientIP resolves the client IP with rs, a nil Resolver trusts no header
+// and only uses RemoteAddr.
+func (rs *Resolver) clientIP(r *http.Request) net.IP {
+	if rs == nil {
+		return remoteIP(r)
+	}
+	return rs.GetIP(r)
+}
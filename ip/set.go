@@ -0,0 +1,347 @@
+package ip
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Set is a concurrency safe set of IPv4 and IPv6 ranges,
+// backed by a binary prefix trie so lookups cost at most one step per address bit.
+type Set struct {
+	mu sync.RWMutex
+	v4 *trieNode
+	v6 *trieNode
+}
+
+type trieNode struct {
+	full     bool
+	children [2]*trieNode
+}
+
+// NewSet returns a Set holding the given CIDRs or single addresses.
+func NewSet(cidrs ...string) (*Set, error) {
+	s := &Set{}
+	for _, cidr := range cidrs {
+		if err := s.Add(cidr); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// MustNewSet is like NewSet but panics on error.
+func MustNewSet(cidrs ...string) *Set {
+	s, err := NewSet(cidrs...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// LoadSet reads a Set from a file with one CIDR or address per line,
+// blank lines and lines starting with "#" are ignored.
+func LoadSet(path string) (*Set, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s := &Set{}
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i != -1 {
+			text = text[:i]
+		}
+		if text = strings.TrimSpace(text); text == "" {
+			continue
+		}
+		if err := s.Add(text); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+	}
+	return s, scanner.Err()
+}
+
+// ParseCIDR parses a CIDR, a single address is treated as a /32 or /128 network.
+func ParseCIDR(s string) (*net.IPNet, error) {
+	if strings.IndexByte(s, '/') == -1 {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address: %q", s)
+		}
+		if v4 := ip.To4(); v4 != nil {
+			return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	return n, err
+}
+
+// Add adds a CIDR or a single address to the set.
+func (s *Set) Add(cidr string) error {
+	n, err := ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	return s.AddNet(n)
+}
+
+// AddNet adds a network to the set,
+// networks with a non-canonical mask such as 255.0.255.0 are rejected.
+// IPv6 networks covering ::ffff:0:0/96, such as ::/0, also add every IPv4 address,
+// as IPv4-mapped addresses are matched as IPv4.
+func (s *Set) AddNet(n *net.IPNet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	root, ip, bits, err := s.root(n)
+	if err != nil {
+		return err
+	}
+	*root = insert(*root, ip, bits, 0)
+	if root == &s.v6 && coversMapped(ip, bits) {
+		s.v4 = insert(s.v4, make(net.IP, net.IPv4len), 0, 0)
+	}
+	return nil
+}
+
+// Remove removes a CIDR or a single address from the set,
+// enclosing ranges are split as needed.
+func (s *Set) Remove(cidr string) error {
+	n, err := ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	return s.RemoveNet(n)
+}
+
+// RemoveNet removes a network from the set,
+// networks with a non-canonical mask are rejected and IPv4 is removed along with
+// ::ffff:0:0/96 like in AddNet.
+func (s *Set) RemoveNet(n *net.IPNet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	root, ip, bits, err := s.root(n)
+	if err != nil {
+		return err
+	}
+	*root = remove(*root, ip, bits, 0)
+	if root == &s.v6 && coversMapped(ip, bits) {
+		s.v4 = nil
+	}
+	return nil
+}
+
+// Contains reports whether ip is inside any range of the set.
+func (s *Set) Contains(ip net.IP) bool {
+	if s == nil || ip == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	node, key := s.v6, ip.To16()
+	if v4 := ip.To4(); v4 != nil {
+		node, key = s.v4, v4
+	}
+	if key == nil {
+		return false
+	}
+	for i := 0; node != nil; i++ {
+		if node.full {
+			return true
+		}
+		if i == len(key)*8 {
+			return false
+		}
+		node = node.children[bit(key, i)]
+	}
+	return false
+}
+
+// Merge adds all ranges of o to s.
+func (s *Set) Merge(o *Set) {
+	for _, n := range o.Nets() {
+		s.AddNet(n)
+	}
+}
+
+// Subtract removes all ranges of o from s.
+func (s *Set) Subtract(o *Set) {
+	for _, n := range o.Nets() {
+		s.RemoveNet(n)
+	}
+}
+
+// Nets returns the minimal list of networks covering the set, IPv4 first.
+func (s *Set) Nets() []*net.IPNet {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var nets []*net.IPNet
+	nets = walk(s.v4, make(net.IP, net.IPv4len), 0, nets)
+	nets = walk(s.v6, make(net.IP, net.IPv6len), 0, nets)
+	return nets
+}
+
+// String returns the networks of the set separated by commas.
+func (s *Set) String() string {
+	nets := s.Nets()
+	parts := make([]string, len(nets))
+	for i, n := range nets {
+		parts[i] = n.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// root returns the trie holding n, IPv4-mapped IPv6 networks are stored as IPv4.
+func (s *Set) root(n *net.IPNet) (**trieNode, net.IP, int, error) {
+	ones, size := n.Mask.Size()
+	if size == 0 {
+		return nil, nil, 0, fmt.Errorf("invalid network mask: %s", n.Mask)
+	}
+	if v4 := n.IP.To4(); v4 != nil {
+		if size == 32 {
+			return &s.v4, v4, ones, nil
+		}
+		if ones >= 96 {
+			return &s.v4, v4, ones - 96, nil
+		}
+	}
+	if size != 128 || n.IP.To16() == nil {
+		return nil, nil, 0, fmt.Errorf("invalid network: %s", n)
+	}
+	return &s.v6, n.IP.To16(), ones, nil
+}
+
+// mappedPrefix is ::ffff:0:0/96, the prefix of IPv4-mapped IPv6 addresses.
+var mappedPrefix = net.IP{10: 0xff, 11: 0xff, 15: 0}
+
+// coversMapped reports whether the IPv6 network of ip and bits holds all IPv4-mapped addresses.
+func coversMapped(ip net.IP, bits int) bool {
+	if bits > 96 {
+		return false
+	}
+	for i := 0; i < bits; i++ {
+		if bit(ip, i) != bit(mappedPrefix, i) {
+			return false
+		}
+	}
+	return true
+}
+
+func bit(ip net.IP, i int) int {
+	return int(ip[i/8]>>(7-uint(i%8))) & 1
+}
+
+func insert(node *trieNode, ip net.IP, bits, depth int) *trieNode {
+	if node == nil {
+		node = &trieNode{}
+	}
+	if node.full {
+		return node
+	}
+	if depth == bits {
+		return &trieNode{full: true}
+	}
+	b := bit(ip, depth)
+	node.children[b] = insert(node.children[b], ip, bits, depth+1)
+	if l, r := node.children[0], node.children[1]; l != nil && r != nil && l.full && r.full {
+		return &trieNode{full: true}
+	}
+	return node
+}
+
+func remove(node *trieNode, ip net.IP, bits, depth int) *trieNode {
+	if node == nil || depth == bits {
+		return nil
+	}
+	if node.full {
+		node = &trieNode{children: [2]*trieNode{{full: true}, {full: true}}}
+	}
+	b := bit(ip, depth)
+	node.children[b] = remove(node.children[b], ip, bits, depth+1)
+	if node.children[0] == nil && node.children[1] == nil {
+		return nil
+	}
+	return node
+}
+
+func walk(node *trieNode, ip net.IP, depth int, nets []*net.IPNet) []*net.IPNet {
+	if node == nil {
+		return nets
+	}
+	if node.full {
+		n := &net.IPNet{IP: make(net.IP, len(ip)), Mask: net.CIDRMask(depth, len(ip)*8)}
+		copy(n.IP, ip)
+		return append(nets, n)
+	}
+	for b, child := range node.children {
+		if b == 1 {
+			ip[depth/8] |= 1 << (7 - uint(depth%8))
+		}
+		nets = walk(child, ip, depth+1, nets)
+		if b == 1 {
+			ip[depth/8] &^= 1 << (7 - uint(depth%8))
+		}
+	}
+	return nets
+}
+
+type tagKey string
+
+// AllowHandler only serves requests whose client IP is in the set,
+// other requests are rejected with 403 Forbidden.
+//
+// The client IP is taken from r.RemoteAddr when resolver is nil.
+// Proxy headers can be forged by any client, so only pass a resolver
+// when the server runs behind a proxy that overwrites the resolver headers, e.g.
+//
+//	s.AllowHandler(&ip.Resolver{Headers: []string{ip.HeaderXRealIP}}, next)
+func (s *Set) AllowHandler(resolver *Resolver, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Contains(resolver.clientIP(r)) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// DenyHandler rejects requests whose client IP is in the set with 403 Forbidden,
+// the client IP is resolved like in AllowHandler.
+func (s *Set) DenyHandler(resolver *Resolver, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Contains(resolver.clientIP(r)) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TagHandler serves every request and records under name whether
+// its client IP is in the set, see Tagged.
+// The client IP is resolved like in AllowHandler.
+func (s *Set) TagHandler(name string, resolver *Resolver, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), tagKey(name), s.Contains(resolver.clientIP(r)))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Tagged reports whether a TagHandler with the given name matched the request.
+func Tagged(ctx context.Context, name string) bool {
+	v, _ := ctx.Value(tagKey(name)).(bool)
+	return v
+}
@@ -0,0 +1,238 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ti/goutil/ip"
+	"github.com/ti/goutil/random"
+)
+
+// HeaderXRequestID carries the request ID of AccessLog.
+const HeaderXRequestID = "X-Request-ID"
+
+// AccessConfig configures AccessLog.
+type AccessConfig struct {
+	// Logger receives one entry per request, the package logger by default.
+	// Its encoder selects the format, such as &CombinedEncoder{}, &JSONEncoder{} or &LogfmtEncoder{}.
+	Logger Logger
+	// Exclude lists paths which are not logged, a trailing "*" matches any suffix.
+	Exclude []string
+	// SlowThreshold logs requests taking at least this long at Warn level, 0 disables it.
+	SlowThreshold time.Duration
+	// RequestIDHeader is read for an incoming request ID and set on the response,
+	// X-Request-ID by default. A random ID is generated when the request has none.
+	RequestIDHeader string
+	// LogQuery adds the query string to the logged request line. It is off by default
+	// because query strings often carry tokens and other secrets.
+	LogQuery bool
+}
+
+// AccessLog returns a middleware logging the method, path, status, bytes, latency, client IP
+// and request ID of every request. The client IP comes from ip.Middleware when it runs first,
+// otherwise from ip.GetIP. The request ID is stored in the request context, see RequestID.
+// Requests whose handler panics are logged at Error level with status 500, then the panic goes on.
+// A nil cfg uses the defaults.
+//
+//	logger := log.NewDefaultLogger(os.Stdout, log.UseEncoder(&log.CombinedEncoder{}))
+//	handler = log.AccessLog(&log.AccessConfig{Logger: logger, Exclude: []string{"/healthz"}})(handler)
+func AccessLog(cfg *AccessConfig) func(http.Handler) http.Handler {
+	if cfg == nil {
+		cfg = &AccessConfig{}
+	}
+	header := cfg.RequestIDHeader
+	if header == "" {
+		header = HeaderXRequestID
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(header)
+			if id == "" || len(id) > 128 {
+				id = random.NewRandomString()
+			}
+			w.Header().Set(header, id)
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			r = r.WithContext(NewContext(ctx, "request_id", id))
+			if excluded(cfg.Exclude, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+			defer func() {
+				if p := recover(); p != nil {
+					logAccess(cfg, r, sw, id, time.Since(start), p)
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(sw, r)
+			logAccess(cfg, r, sw, id, time.Since(start), nil)
+		})
+	}
+}
+
+// logAccess writes the entry of a request, p is the value of a panic of the handler.
+func logAccess(cfg *AccessConfig, r *http.Request, sw *statusWriter, id string, latency time.Duration, p interface{}) {
+	clientIP := ip.FromContext(r.Context())
+	if clientIP == nil {
+		clientIP = ip.GetIP(r)
+	}
+	var addr string
+	if clientIP != nil {
+		addr = clientIP.String()
+	}
+	status := sw.Status()
+	if p != nil && sw.status == 0 {
+		status = http.StatusInternalServerError
+	}
+	keyvals := []interface{}{
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status", status,
+		"bytes", sw.bytes,
+		"latency", latency,
+		"ip", addr,
+		"request_id", id,
+	}
+	if user, _, ok := r.BasicAuth(); ok {
+		keyvals = append(keyvals, "user", user)
+	}
+	keyvals = append(keyvals, "referer", r.Referer(), "user_agent", r.UserAgent())
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = l
+	}
+	lvl := LevelInfo
+	if cfg.SlowThreshold > 0 && latency >= cfg.SlowThreshold {
+		lvl = LevelWarn
+	}
+	if p != nil {
+		lvl = LevelError
+		keyvals = append(keyvals, "panic", p)
+	}
+	target := r.URL.EscapedPath()
+	if cfg.LogQuery {
+		target = r.URL.RequestURI()
+	}
+	logAt(WithFields(logger, keyvals...), lvl, r.Method+" "+target+" "+r.Proto)
+}
+
+type requestIDKey struct{}
+
+// RequestID returns the request ID stored by AccessLog, "" if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func excluded(patterns []string, path string) bool {
+	for _, p := range patterns {
+		if prefix := strings.TrimSuffix(p, "*"); prefix != p {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// statusWriter records the status code and the size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Status returns the status code sent, 200 if the handler wrote nothing.
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("log: response does not support hijacking")
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// CombinedEncoder writes entries of AccessLog in the Combined Log Format of Apache and Nginx,
+// other entries get "-" for the missing values.
+//
+//	192.0.2.1 - - [18/Sep/2018:19:00:00 +0800] "GET /index.html HTTP/1.1" 200 512 "-" "curl/7.61.0"
+type CombinedEncoder struct{}
+
+func (enc *CombinedEncoder) Encode(buf *bytes.Buffer, e *Entry) error {
+	fields := make(map[string]interface{}, len(e.Fields)/2)
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		fields[fieldKey(e.Fields[i])] = e.Fields[i+1]
+	}
+	value := func(key string) string {
+		v, ok := fields[key]
+		if !ok {
+			return "-"
+		}
+		s := fmt.Sprint(fieldValue(v))
+		if s == "" || s == "0" {
+			return "-"
+		}
+		return s
+	}
+	buf.WriteString(value("ip"))
+	buf.WriteString(" - ")
+	buf.WriteString(value("user"))
+	buf.WriteString(" [")
+	buf.WriteString(e.Time.Format("02/Jan/2006:15:04:05 -0700"))
+	buf.WriteString("] ")
+	buf.WriteString(strconv.Quote(e.Message))
+	buf.WriteByte(' ')
+	buf.WriteString(value("status"))
+	buf.WriteByte(' ')
+	buf.WriteString(value("bytes"))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.Quote(value("referer")))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.Quote(value("user_agent")))
+	buf.WriteByte('\n')
+	return nil
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewDefaultLogger(&buf, UseLevel(&LevelVar{}), UseEncoder(&LogfmtEncoder{}))
+	var ctxID string
+	handler := AccessLog(&AccessConfig{Logger: logger, Exclude: []string{"/healthz", "/static/*"}, SlowThreshold: 20 * time.Millisecond})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctxID = RequestID(r.Context())
+			if r.URL.Path == "/slow" {
+				time.Sleep(25 * time.Millisecond)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("hello"))
+		}))
+
+	for _, path := range []string{"/healthz", "/static/app.js", "/users?id=1", "/slow"} {
+		r := httptest.NewRequest("POST", path, nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		r.Header.Set(HeaderXRequestID, "req-1")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Header().Get(HeaderXRequestID) != "req-1" || ctxID != "req-1" {
+			t.Fatal("request ID is not propagated:", w.Header(), ctxID)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `level=info`) ||
+		!strings.Contains(lines[0], `msg="POST /users HTTP/1.1" method=POST path=/users status=201 bytes=5 latency=`) ||
+		!strings.Contains(lines[0], "ip=192.0.2.1 request_id=req-1") || !strings.Contains(lines[1], "level=warn") {
+		t.Fatal("unexpected access log:", buf.String())
+	}
+
+	buf.Reset()
+	r := httptest.NewRequest("GET", "/", nil)
+	handler = AccessLog(&AccessConfig{Logger: NewDefaultLogger(&buf, UseLevel(&LevelVar{}), UseEncoder(&CombinedEncoder{}))})(http.NotFoundHandler())
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if out := buf.String(); !strings.HasPrefix(out, "192.0.2.1 - - [") || !strings.HasSuffix(out, `] "GET / HTTP/1.1" 404 19 "-" "-"`+"\n") {
+		t.Fatal("unexpected combined log:", out)
+	}
+
+	buf.Reset()
+	handler = AccessLog(&AccessConfig{Logger: logger, LogQuery: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Fatal("the panic is not propagated:", p)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users?id=1", nil))
+	}()
+	if out := buf.String(); !strings.Contains(out, `level=error`) || !strings.Contains(out, `msg="GET /users?id=1 HTTP/1.1"`) ||
+		!strings.Contains(out, "status=500") || !strings.Contains(out, "panic=boom") {
+		t.Fatal("unexpected panic log:", out)
+	}
+}
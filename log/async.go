@@ -0,0 +1,161 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// FullPolicy decides what an AsyncWriter does when its buffer is full.
+type FullPolicy int
+
+const (
+	// BlockWhenFull makes Write wait for free space.
+	BlockWhenFull FullPolicy = iota
+	// DropNewest discards the line being written.
+	DropNewest
+	// DropOldest discards the oldest buffered line.
+	DropOldest
+)
+
+// ErrWriterClosed is returned when writing to a closed AsyncWriter.
+var ErrWriterClosed = errors.New("log: writer closed")
+
+// Flusher is implemented by outputs buffering lines, Fatal flushes the output before exiting.
+type Flusher interface {
+	Flush() error
+}
+
+// AsyncWriter buffers lines in a bounded ring and writes them to the wrapped
+// writer from a background goroutine, so logging never waits for slow outputs
+// unless the policy is BlockWhenFull.
+type AsyncWriter struct {
+	w      io.Writer
+	policy FullPolicy
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	ring     [][]byte
+	head     int
+	count    int
+	queued   uint64 // lines queued since the start
+	finished uint64 // lines written or dropped from the ring since the start
+	closed   bool
+	err      error
+
+	dropped uint64
+	done    chan struct{}
+}
+
+// NewAsyncWriter returns an AsyncWriter buffering up to size lines for w.
+func NewAsyncWriter(w io.Writer, size int, policy FullPolicy) *AsyncWriter {
+	if size <= 0 {
+		size = 1024
+	}
+	a := &AsyncWriter{
+		w:      w,
+		policy: policy,
+		ring:   make([][]byte, size),
+		done:   make(chan struct{}),
+	}
+	a.cond = sync.NewCond(&a.mu)
+	go a.loop()
+	return a
+}
+
+// Write queues a copy of p, it only fails once the writer is closed.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for !a.closed && a.count == len(a.ring) {
+		switch a.policy {
+		case DropNewest:
+			atomic.AddUint64(&a.dropped, 1)
+			return len(p), nil
+		case DropOldest:
+			a.ring[a.head] = nil
+			a.head = (a.head + 1) % len(a.ring)
+			a.count--
+			a.finished++
+			atomic.AddUint64(&a.dropped, 1)
+		default:
+			a.cond.Wait()
+		}
+	}
+	if a.closed {
+		return 0, ErrWriterClosed
+	}
+	a.ring[(a.head+a.count)%len(a.ring)] = line
+	a.count++
+	a.queued++
+	a.cond.Broadcast()
+	return len(p), nil
+}
+
+// Dropped returns the number of lines discarded because the buffer was full.
+func (a *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// Flush waits until the lines queued before the call are written, then flushes the wrapped writer
+// if it is a Flusher. Lines queued during the wait are not waited for, so Flush returns
+// even while other goroutines keep logging. It returns the first write error since the last Flush.
+func (a *AsyncWriter) Flush() error {
+	a.mu.Lock()
+	for seq := a.queued; a.finished < seq; {
+		a.cond.Wait()
+	}
+	err := a.err
+	a.err = nil
+	a.mu.Unlock()
+	if f, ok := a.w.(Flusher); ok {
+		if ferr := f.Flush(); err == nil {
+			err = ferr
+		}
+	}
+	return err
+}
+
+// Close flushes the queued lines and stops the background goroutine,
+// the wrapped writer is left open.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrWriterClosed
+	}
+	a.closed = true
+	a.cond.Broadcast()
+	a.mu.Unlock()
+	<-a.done
+	return a.Flush()
+}
+
+func (a *AsyncWriter) loop() {
+	defer close(a.done)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for {
+		for a.count == 0 && !a.closed {
+			a.cond.Wait()
+		}
+		if a.count == 0 {
+			return
+		}
+		line := a.ring[a.head]
+		a.ring[a.head] = nil
+		a.head = (a.head + 1) % len(a.ring)
+		a.count--
+		a.mu.Unlock()
+		_, err := a.w.Write(line)
+		a.mu.Lock()
+		if err != nil && a.err == nil {
+			a.err = err
+		}
+		a.finished++
+		a.cond.Broadcast()
+	}
+}
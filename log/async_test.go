@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func TestAsyncWriter(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewAsyncWriter(&buf, 4, BlockWhenFull)
+	for i := 0; i < 100; i++ {
+		a.Write([]byte("line\n"))
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "line\n"); n != 100 {
+		t.Fatal("expect 100 lines but got", n)
+	}
+	if _, err := a.Write([]byte("late\n")); err != ErrWriterClosed {
+		t.Fatal("write after close should fail", err)
+	}
+}
+
+func TestAsyncWriterDrop(t *testing.T) {
+	for policy, expect := range map[FullPolicy]string{
+		DropNewest: "0\n1\n2\n",
+		DropOldest: "0\n4\n5\n",
+	} {
+		w := &blockingWriter{release: make(chan struct{})}
+		a := NewAsyncWriter(w, 2, policy)
+		a.Write([]byte("0\n"))
+		// wait for the first line to be taken by the background goroutine
+		for {
+			a.mu.Lock()
+			taken := a.count == 0
+			a.mu.Unlock()
+			if taken {
+				break
+			}
+		}
+		for _, line := range []string{"1\n", "2\n", "3\n", "4\n", "5\n"} {
+			a.Write([]byte(line))
+		}
+		close(w.release)
+		a.Close()
+		if w.buf.String() != expect || a.Dropped() != 3 {
+			t.Fatal("policy", policy, "wrote", w.buf.String(), "dropped", a.Dropped())
+		}
+	}
+}
+
+func TestAsyncWriterFlush(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	close(w.release)
+	a := NewAsyncWriter(w, 4, BlockWhenFull)
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				a.Write([]byte("line\n"))
+			}
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		if err := a.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	a.Close()
+}
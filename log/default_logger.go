@@ -2,9 +2,15 @@ package log
 
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -22,8 +28,8 @@ var ColorAble bool
 
 type colorAttribute int
 
-func color(s string, c colorAttribute) string{
-	if ColorAble {
+func colorize(s string, c colorAttribute, on bool) string {
+	if on {
 		return fmt.Sprintf("\u001b[%vm%s \u001b[0m",c,s)
 	} else {
 		return s
@@ -32,75 +38,310 @@ func color(s string, c colorAttribute) string{
 
 type defaultLogger struct {
 	*log.Logger
+	levels  *LevelVar
+	encoder *encoderVar
+	mu      *sync.Mutex
+	fields  []interface{}
+	sinks   []Sink
+
+	callerSkip int
+	stackLevel Level
+	exit       func(code int)
+	skipHooks  bool
+	now        func() time.Time
 }
 
-func (l *defaultLogger) Log(keyvals ...interface{}) {
-	var dist string
-	var s bool
-	for _, v := range keyvals {
-		if s {
-			dist += fmt.Sprint("=",v, " ")
-		} else {
-			dist += fmt.Sprint(v)
+// Option configures a logger created by NewDefaultLogger.
+type Option func(l *defaultLogger)
+
+// UseLevel makes the logger use its own level threshold instead of the package one.
+func UseLevel(v *LevelVar) Option {
+	return func(l *defaultLogger) {
+		l.levels = v
+	}
+}
+
+// UseEncoder selects the output format of the logger, such as &JSONEncoder{} or &LogfmtEncoder{},
+// the default is a TextEncoder.
+func UseEncoder(enc Encoder) Option {
+	return func(l *defaultLogger) {
+		l.encoder = newEncoderVar(enc)
+	}
+}
+
+// CallerSkip makes the logger report the caller n frames above the code calling it,
+// for helpers and wrappers logging on behalf of their callers.
+func CallerSkip(n int) Option {
+	return func(l *defaultLogger) {
+		l.callerSkip += n
+	}
+}
+
+// AddStacktrace attaches the stack of the caller to entries of lvl and above.
+func AddStacktrace(lvl Level) Option {
+	return func(l *defaultLogger) {
+		l.stackLevel = lvl
+	}
+}
+
+// UseExit replaces the function ending the process after Fatal, Fatal returns when fn does.
+// Hooks added by RegisterExitHook still run first, unless SkipExitHooks is given.
+func UseExit(fn func(code int)) Option {
+	return func(l *defaultLogger) {
+		l.exit = fn
+	}
+}
+
+// SkipExitHooks makes Fatal skip the hooks added by RegisterExitHook,
+// for loggers whose Fatal does not end the process, such as test recorders.
+func SkipExitHooks() Option {
+	return func(l *defaultLogger) {
+		l.skipHooks = true
+	}
+}
+
+// UseClock sets the source of entry timestamps, for deterministic output in tests.
+func UseClock(now func() time.Time) Option {
+	return func(l *defaultLogger) {
+		l.now = now
+	}
+}
+
+// noStacktrace is above every level, so no entry gets a stack trace.
+const noStacktrace = LevelPanic + 1
+
+func newDefaultLogger(logger *log.Logger, opts ...Option) *defaultLogger {
+	l := &defaultLogger{
+		Logger:     logger,
+		levels:     levels,
+		encoder:    newEncoderVar(nil),
+		mu:         &sync.Mutex{},
+		stackLevel: noStacktrace,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+var bufPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// output encodes an entry and writes it, calldepth is counted the same way as in log.Logger.Output.
+func (l *defaultLogger) output(calldepth int, lvl Level, msg string, fields []interface{}) {
+	var one [1]uintptr
+	pcs := one[:]
+	if lvl >= l.stackLevel {
+		pcs = make([]uintptr, 32)
+	}
+	n := runtime.Callers(calldepth+1, pcs)
+	l.outputPCs(pcs[:n], lvl, msg, fields)
+}
+
+// outputPCs writes an entry for the caller at pcs[0], the other pcs are only used for the stack trace.
+func (l *defaultLogger) outputPCs(pcs []uintptr, lvl Level, msg string, fields []interface{}) {
+	e := Entry{Time: l.now(), Level: lvl, Message: msg, Fields: appendFields(l.fields, fields)}
+	if len(pcs) > 0 {
+		frames := runtime.CallersFrames(pcs)
+		frame, more := frames.Next()
+		e.File, e.Line = frame.File, frame.Line
+		if lvl >= l.stackLevel {
+			var stack bytes.Buffer
+			for {
+				fmt.Fprintf(&stack, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+				if !more {
+					break
+				}
+				frame, more = frames.Next()
+			}
+			e.Stack = stack.String()
+		}
+	}
+	if len(l.sinks) > 0 {
+		for _, sink := range l.sinks {
+			if lvl >= sink.Level {
+				l.write(sink.Writer, sink.Encoder, &e)
+			}
+		}
+		return
+	}
+	enc := l.encoder.Load()
+	if enc == nil {
+		enc = &TextEncoder{Flags: l.Flags(), Prefix: l.Prefix()}
+	}
+	l.write(l.Writer(), enc, &e)
+}
+
+// encoderVar holds the encoder of a logger and of the children created by With
+// and AddCallerSkip, so it can be replaced while they log. A nil encoder is a
+// TextEncoder following the flags and prefix of the embedded log.Logger.
+type encoderVar struct {
+	v atomic.Value
+}
+
+type encoderBox struct {
+	enc Encoder
+}
+
+func newEncoderVar(enc Encoder) *encoderVar {
+	v := &encoderVar{}
+	v.Store(enc)
+	return v
+}
+
+func (v *encoderVar) Load() Encoder {
+	return v.v.Load().(encoderBox).enc
+}
+
+func (v *encoderVar) Store(enc Encoder) {
+	v.v.Store(encoderBox{enc})
+}
+
+// depth is the calldepth of the code calling a method of the logger.
+func (l *defaultLogger) depth() int {
+	return calldepth + l.callerSkip
+}
+
+// AddCallerSkip returns a child logger reporting the caller n frames further up.
+func (l *defaultLogger) AddCallerSkip(n int) Logger {
+	child := *l
+	child.callerSkip += n
+	return &child
+}
+
+// logPC writes an entry for the caller at pc, such as the source of a slog.Record.
+func (l *defaultLogger) logPC(pc uintptr, lvl Level, msg string, fields []interface{}) {
+	if !l.levels.Enabled(pcModule(pc), lvl) {
+		return
+	}
+	var pcs []uintptr
+	if pc != 0 {
+		pcs = []uintptr{pc}
+	}
+	l.outputPCs(pcs, lvl, msg, fields)
+}
+
+func (l *defaultLogger) write(w io.Writer, enc Encoder, e *Entry) {
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := enc.Encode(buf, e); err != nil {
+		fmt.Fprintf(os.Stderr, "log: encode entry: %v\n", err)
+	} else {
+		l.mu.Lock()
+		w.Write(buf.Bytes())
+		l.mu.Unlock()
+	}
+	bufPool.Put(buf)
+}
+
+// flush writes out lines buffered by the output, so none is lost before exiting.
+func (l *defaultLogger) flush() {
+	if f, ok := l.Writer().(Flusher); ok {
+		f.Flush()
+	}
+	for _, sink := range l.sinks {
+		if f, ok := sink.Writer.(Flusher); ok {
+			f.Flush()
 		}
-		s = !s
 	}
-	if s {
-		dist += "=null"
+}
+
+// Enabled reports whether entries of lvl logged by the caller are written.
+func (l *defaultLogger) Enabled(lvl Level) bool {
+	return l.levels.enabled(l.depth(), lvl)
+}
+
+func (l *defaultLogger) Log(keyvals ...interface{}) {
+	if !l.levels.enabled(l.depth(), LevelInfo) {
+		return
 	}
-	l.Output(calldepth, header(color("LOG",blue), dist))
+	l.output(l.depth(), LevelInfo, "", keyvals)
 }
 
 func (l *defaultLogger) Debug(v ...interface{}) {
-	l.Output(calldepth, header("DEBUG", fmt.Sprint(v...)))
+	if !l.levels.enabled(l.depth(), LevelDebug) {
+		return
+	}
+	l.output(l.depth(), LevelDebug, fmt.Sprint(v...), nil)
 }
 
 func (l *defaultLogger) Debugf(format string, v ...interface{}) {
-	l.Output(calldepth, header("DEBUG", fmt.Sprintf(format, v...)))
+	if !l.levels.enabled(l.depth(), LevelDebug) {
+		return
+	}
+	l.output(l.depth(), LevelDebug, fmt.Sprintf(format, v...), nil)
 }
 
 func (l *defaultLogger) Info(v ...interface{}) {
-	l.Output(calldepth, header(color("INFO",green), fmt.Sprint(v...)))
+	if !l.levels.enabled(l.depth(), LevelInfo) {
+		return
+	}
+	l.output(l.depth(), LevelInfo, fmt.Sprint(v...), nil)
 }
 
 func (l *defaultLogger) Infof(format string, v ...interface{}) {
-	l.Output(calldepth, header(color("INFO",green), fmt.Sprintf(format, v...)))
+	if !l.levels.enabled(l.depth(), LevelInfo) {
+		return
+	}
+	l.output(l.depth(), LevelInfo, fmt.Sprintf(format, v...), nil)
 }
 
 func (l *defaultLogger) Warn(v ...interface{}) {
-	l.Output(calldepth, header(color("WARN",yellow), fmt.Sprint(v...)))
+	if !l.levels.enabled(l.depth(), LevelWarn) {
+		return
+	}
+	l.output(l.depth(), LevelWarn, fmt.Sprint(v...), nil)
 }
 
 func (l *defaultLogger) Warnf(format string, v ...interface{}) {
-	l.Output(calldepth, header(color("WARN",yellow), fmt.Sprintf(format, v...)))
+	if !l.levels.enabled(l.depth(), LevelWarn) {
+		return
+	}
+	l.output(l.depth(), LevelWarn, fmt.Sprintf(format, v...), nil)
 }
 
 func (l *defaultLogger) Error(v ...interface{}) {
-	l.Output(calldepth, header(color("ERROR",red), fmt.Sprint(v...)))
+	if !l.levels.enabled(l.depth(), LevelError) {
+		return
+	}
+	l.output(l.depth(), LevelError, fmt.Sprint(v...), nil)
 }
 
 func (l *defaultLogger) Errorf(format string, v ...interface{}) {
-	l.Output(calldepth, header(color("ERROR",red), fmt.Sprintf(format, v...)))
+	if !l.levels.enabled(l.depth(), LevelError) {
+		return
+	}
+	l.output(l.depth(), LevelError, fmt.Sprintf(format, v...), nil)
 }
 
 func (l *defaultLogger) Fatal(v ...interface{}) {
-	l.Output(calldepth, header(color("FATAL",magenta), fmt.Sprint(v...)))
-	os.Exit(1)
+	if l.levels.enabled(l.depth(), LevelFatal) {
+		l.output(l.depth(), LevelFatal, fmt.Sprint(v...), nil)
+	}
+	exit(l.exit, l.flush, l.skipHooks, 1)
 }
 
 func (l *defaultLogger) Fatalf(format string, v ...interface{}) {
-	l.Output(calldepth, header(color("FATAL",magenta), fmt.Sprintf(format, v...)))
-	os.Exit(1)
+	if l.levels.enabled(l.depth(), LevelFatal) {
+		l.output(l.depth(), LevelFatal, fmt.Sprintf(format, v...), nil)
+	}
+	exit(l.exit, l.flush, l.skipHooks, 1)
 }
 
 func (l *defaultLogger) Panic(v ...interface{}) {
-	l.Logger.Panic(v)
+	msg := fmt.Sprint(v...)
+	if l.levels.enabled(l.depth(), LevelPanic) {
+		l.output(l.depth(), LevelPanic, msg, nil)
+	}
+	l.flush()
+	panic(msg)
 }
 
 func (l *defaultLogger) Panicf(format string, v ...interface{}) {
-	l.Logger.Panicf(format, v...)
-}
-
-func header(lvl, msg string) string {
-	return fmt.Sprintf("%s: %s", lvl, msg)
+	msg := fmt.Sprintf(format, v...)
+	if l.levels.enabled(l.depth(), LevelPanic) {
+		l.output(l.depth(), LevelPanic, msg, nil)
+	}
+	l.flush()
+	panic(msg)
 }
@@ -0,0 +1,294 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Entry is a single log record handed to an Encoder.
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	File    string
+	Line    int
+	Message string
+	// Fields are key value pairs, like the arguments of Log.
+	Fields []interface{}
+	// Stack is the stack trace of the caller, set by the AddStacktrace option.
+	Stack string
+}
+
+// Caller returns "file.go:line", or "" if the caller is unknown.
+func (e *Entry) Caller() string {
+	if e.File == "" {
+		return ""
+	}
+	return filepath.Base(e.File) + ":" + strconv.Itoa(e.Line)
+}
+
+// Encoder formats an Entry into buf, one entry per line.
+type Encoder interface {
+	Encode(buf *bytes.Buffer, e *Entry) error
+}
+
+// TextEncoder writes the human readable format of the standard library logger,
+// Flags and Prefix take the same values as log.SetFlags and log.SetPrefix. Entries without a message, such as
+// the ones written by Log, are labelled LOG. Level labels are colored when Color
+// or ColorAble is set.
+//
+//	2018/09/18 19:00:00 main.go:12: INFO: hello user=1
+type TextEncoder struct {
+	Flags  int
+	Prefix string
+	Color  bool
+}
+
+// NewTextEncoder returns the TextEncoder used by default.
+func NewTextEncoder() *TextEncoder {
+	return &TextEncoder{Flags: log.LstdFlags | log.Lshortfile}
+}
+
+func (enc *TextEncoder) Encode(buf *bytes.Buffer, e *Entry) error {
+	var tmp [20]byte
+	if enc.Flags&log.Lmsgprefix == 0 {
+		buf.WriteString(enc.Prefix)
+	}
+	if enc.Flags&(log.Ldate|log.Ltime|log.Lmicroseconds) != 0 {
+		t := e.Time
+		if enc.Flags&log.LUTC != 0 {
+			t = t.UTC()
+		}
+		if enc.Flags&log.Ldate != 0 {
+			buf.Write(t.AppendFormat(tmp[:0], "2006/01/02 "))
+		}
+		if enc.Flags&log.Lmicroseconds != 0 {
+			buf.Write(t.AppendFormat(tmp[:0], "15:04:05.000000 "))
+		} else if enc.Flags&log.Ltime != 0 {
+			buf.Write(t.AppendFormat(tmp[:0], "15:04:05 "))
+		}
+	}
+	if enc.Flags&(log.Lshortfile|log.Llongfile) != 0 {
+		file := e.File
+		if file == "" {
+			file = "???"
+		} else if enc.Flags&log.Lshortfile != 0 {
+			file = filepath.Base(file)
+		}
+		buf.WriteString(file)
+		buf.WriteByte(':')
+		buf.WriteString(strconv.Itoa(e.Line))
+		buf.WriteString(": ")
+	}
+	if enc.Flags&log.Lmsgprefix != 0 {
+		buf.WriteString(enc.Prefix)
+	}
+	buf.WriteString(levelLabel(e, enc.Color || ColorAble))
+	buf.WriteString(": ")
+	buf.WriteString(e.Message)
+	for i := 0; i < len(e.Fields); i += 2 {
+		if i > 0 || e.Message != "" {
+			buf.WriteByte(' ')
+		}
+		writeLogfmtPair(buf, e.Fields, i)
+	}
+	if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(e.Stack)
+	return nil
+}
+
+func levelLabel(e *Entry, colored bool) string {
+	if e.Message == "" && len(e.Fields) > 0 {
+		return colorize("LOG", blue, colored)
+	}
+	switch e.Level {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return colorize("INFO", green, colored)
+	case LevelWarn:
+		return colorize("WARN", yellow, colored)
+	case LevelError:
+		return colorize("ERROR", red, colored)
+	case LevelFatal:
+		return colorize("FATAL", magenta, colored)
+	case LevelPanic:
+		return colorize("PANIC", magenta, colored)
+	}
+	return e.Level.String()
+}
+
+// JSONEncoder writes one JSON object per line with "ts", "level", "caller", "msg"
+// and the entry fields. TimeFormat defaults to time.RFC3339Nano.
+//
+//	{"ts":"2018-09-18T19:00:00+08:00","level":"info","caller":"main.go:12","msg":"hello","user":1}
+type JSONEncoder struct {
+	TimeFormat string
+}
+
+func (enc *JSONEncoder) Encode(buf *bytes.Buffer, e *Entry) error {
+	format := enc.TimeFormat
+	if format == "" {
+		format = time.RFC3339Nano
+	}
+	buf.WriteString(`{"ts":`)
+	writeJSONString(buf, e.Time.Format(format))
+	buf.WriteString(`,"level":`)
+	writeJSONString(buf, e.Level.String())
+	if caller := e.Caller(); caller != "" {
+		buf.WriteString(`,"caller":`)
+		writeJSONString(buf, caller)
+	}
+	if e.Message != "" {
+		buf.WriteString(`,"msg":`)
+		writeJSONString(buf, e.Message)
+	}
+	for i := 0; i < len(e.Fields); i += 2 {
+		buf.WriteByte(',')
+		writeJSONString(buf, fieldKey(e.Fields[i]))
+		buf.WriteByte(':')
+		if i+1 >= len(e.Fields) {
+			buf.WriteString("null")
+			continue
+		}
+		b, err := json.Marshal(fieldValue(e.Fields[i+1]))
+		if err != nil {
+			writeJSONString(buf, fmt.Sprint(e.Fields[i+1]))
+			continue
+		}
+		buf.Write(b)
+	}
+	if e.Stack != "" {
+		buf.WriteString(`,"stack":`)
+		writeJSONString(buf, e.Stack)
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
+
+// LogfmtEncoder writes one line of space separated key=value pairs per entry,
+// values containing spaces, quotes, "=" or control characters are quoted.
+//
+//	ts=2018-09-18T19:00:00+08:00 level=info caller=main.go:12 msg="hello world" user=1
+type LogfmtEncoder struct {
+	TimeFormat string
+}
+
+func (enc *LogfmtEncoder) Encode(buf *bytes.Buffer, e *Entry) error {
+	format := enc.TimeFormat
+	if format == "" {
+		format = time.RFC3339Nano
+	}
+	buf.WriteString("ts=")
+	writeLogfmtValue(buf, e.Time.Format(format))
+	buf.WriteString(" level=")
+	buf.WriteString(e.Level.String())
+	if caller := e.Caller(); caller != "" {
+		buf.WriteString(" caller=")
+		writeLogfmtValue(buf, caller)
+	}
+	if e.Message != "" {
+		buf.WriteString(" msg=")
+		writeLogfmtValue(buf, e.Message)
+	}
+	for i := 0; i < len(e.Fields); i += 2 {
+		buf.WriteByte(' ')
+		writeLogfmtPair(buf, e.Fields, i)
+	}
+	if e.Stack != "" {
+		buf.WriteString(" stack=")
+		writeLogfmtValue(buf, e.Stack)
+	}
+	buf.WriteByte('\n')
+	return nil
+}
+
+func writeLogfmtPair(buf *bytes.Buffer, keyvals []interface{}, i int) {
+	key := []byte(fieldKey(keyvals[i]))
+	for j, c := range key {
+		if c <= ' ' || c == '=' || c == '"' {
+			key[j] = '_'
+		}
+	}
+	buf.Write(key)
+	buf.WriteByte('=')
+	if i+1 >= len(keyvals) {
+		buf.WriteString("null")
+		return
+	}
+	v := fieldValue(keyvals[i+1])
+	if v == nil {
+		buf.WriteString("null")
+		return
+	}
+	writeLogfmtValue(buf, fmt.Sprint(v))
+}
+
+func writeLogfmtValue(buf *bytes.Buffer, s string) {
+	if needsQuote(s) {
+		buf.WriteString(strconv.Quote(s))
+	} else {
+		buf.WriteString(s)
+	}
+}
+
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldKey(key interface{}) string {
+	if s, ok := key.(string); ok {
+		return s
+	}
+	return fmt.Sprint(key)
+}
+
+// fieldValue turns times, errors and Stringers into strings so they encode readably.
+// Typed nil errors and Stringers are written as <nil> like fmt does, their methods may panic.
+func fieldValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	case error:
+		if isNil(t) {
+			return "<nil>"
+		}
+		return t.Error()
+	case fmt.Stringer:
+		if isNil(t) {
+			return "<nil>"
+		}
+		return t.String()
+	}
+	return v
+}
+
+func isNil(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package log
+
+import (
+	"os"
+	"sync"
+)
+
+var (
+	exitMu    sync.Mutex
+	exitFunc  = os.Exit
+	exitHooks []func()
+)
+
+// SetExitFunc replaces os.Exit as the function ending the process after Fatal,
+// for loggers created without UseExit. Fatal returns when fn does.
+func SetExitFunc(fn func(code int)) {
+	exitMu.Lock()
+	if fn == nil {
+		fn = os.Exit
+	}
+	exitFunc = fn
+	exitMu.Unlock()
+}
+
+// RegisterExitHook adds fn to the functions run by Fatal before the process exits, such as
+// closing connections or flushing metrics. Hooks run in reverse order of registration.
+func RegisterExitHook(fn func()) {
+	exitMu.Lock()
+	exitHooks = append(exitHooks, fn)
+	exitMu.Unlock()
+}
+
+// exit runs the exit hooks unless skipHooks is set and flush, then ends the process with fn,
+// or with the package exit function if fn is nil.
+func exit(fn func(code int), flush func(), skipHooks bool, code int) {
+	exitMu.Lock()
+	hooks := exitHooks
+	if skipHooks {
+		hooks = nil
+	}
+	if fn == nil {
+		fn = exitFunc
+	}
+	exitMu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+	if flush != nil {
+		flush()
+	}
+	fn(code)
+}
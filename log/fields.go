@@ -0,0 +1,173 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FieldLogger is a Logger able to create child loggers that add fields to every entry.
+type FieldLogger interface {
+	Logger
+	With(keyvals ...interface{}) Logger
+}
+
+// With returns a child of the package logger adding keyvals to every entry.
+func With(keyvals ...interface{}) Logger {
+	return WithFields(l, keyvals...)
+}
+
+// WithContext returns a child of the package logger adding the fields carried by ctx,
+// see NewContext and RegisterContextFields.
+func WithContext(ctx context.Context) Logger {
+	return WithFields(l, FieldsFromContext(ctx)...)
+}
+
+// WithFields returns a child of logger adding keyvals to every entry. Loggers which are not
+// a FieldLogger get the fields appended to their messages in key=value form.
+func WithFields(logger Logger, keyvals ...interface{}) Logger {
+	if len(keyvals) == 0 {
+		return logger
+	}
+	if fl, ok := logger.(FieldLogger); ok {
+		return fl.With(keyvals...)
+	}
+	return &fieldLogger{Logger: AddCallerSkip(logger, 1), fields: keyvals}
+}
+
+func (l *defaultLogger) With(keyvals ...interface{}) Logger {
+	child := *l
+	child.fields = appendFields(l.fields, keyvals)
+	return &child
+}
+
+// WithContext returns a child logger adding the fields carried by ctx.
+func (l *defaultLogger) WithContext(ctx context.Context) Logger {
+	return WithFields(l, FieldsFromContext(ctx)...)
+}
+
+// appendFields never modifies the backing array of fields, which is shared by child loggers.
+func appendFields(fields []interface{}, keyvals []interface{}) []interface{} {
+	if len(fields) == 0 {
+		return keyvals
+	}
+	if len(keyvals) == 0 {
+		return fields
+	}
+	ret := make([]interface{}, 0, len(fields)+len(keyvals))
+	ret = append(ret, fields...)
+	return append(ret, keyvals...)
+}
+
+type contextKey struct{}
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   []func(ctx context.Context) []interface{}
+)
+
+// NewContext returns a copy of ctx carrying keyvals in addition to the fields ctx already has,
+// such as a request id. They are added to entries by WithContext.
+func NewContext(ctx context.Context, keyvals ...interface{}) context.Context {
+	fields, _ := ctx.Value(contextKey{}).([]interface{})
+	return context.WithValue(ctx, contextKey{}, appendFields(fields, keyvals))
+}
+
+// RegisterContextFields adds a function extracting fields from a context,
+// use it to log trace ids stored by other libraries.
+func RegisterContextFields(fn func(ctx context.Context) []interface{}) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors = append(extractors, fn)
+}
+
+// FieldsFromContext returns the fields stored by NewContext followed by the registered extractors.
+func FieldsFromContext(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(contextKey{}).([]interface{})
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	for _, fn := range extractors {
+		fields = appendFields(fields, fn(ctx))
+	}
+	return fields
+}
+
+// fieldLogger adds fields to a Logger that does not support them.
+type fieldLogger struct {
+	Logger
+	fields []interface{}
+}
+
+func (l *fieldLogger) With(keyvals ...interface{}) Logger {
+	return &fieldLogger{Logger: l.Logger, fields: appendFields(l.fields, keyvals)}
+}
+
+func (l *fieldLogger) AddCallerSkip(n int) Logger {
+	return &fieldLogger{Logger: AddCallerSkip(l.Logger, n), fields: l.fields}
+}
+
+func (l *fieldLogger) suffix(msg string) string {
+	var buf bytes.Buffer
+	buf.WriteString(msg)
+	for i := 0; i < len(l.fields); i += 2 {
+		buf.WriteByte(' ')
+		writeLogfmtPair(&buf, l.fields, i)
+	}
+	return buf.String()
+}
+
+func (l *fieldLogger) Log(keyvals ...interface{}) {
+	l.Logger.Log(appendFields(l.fields, keyvals)...)
+}
+
+func (l *fieldLogger) Debug(v ...interface{}) {
+	l.Logger.Debug(l.suffix(fmt.Sprint(v...)))
+}
+
+func (l *fieldLogger) Debugf(format string, v ...interface{}) {
+	l.Logger.Debug(l.suffix(fmt.Sprintf(format, v...)))
+}
+
+func (l *fieldLogger) Info(v ...interface{}) {
+	l.Logger.Info(l.suffix(fmt.Sprint(v...)))
+}
+
+func (l *fieldLogger) Infof(format string, v ...interface{}) {
+	l.Logger.Info(l.suffix(fmt.Sprintf(format, v...)))
+}
+
+func (l *fieldLogger) Warn(v ...interface{}) {
+	l.Logger.Warn(l.suffix(fmt.Sprint(v...)))
+}
+
+func (l *fieldLogger) Warnf(format string, v ...interface{}) {
+	l.Logger.Warn(l.suffix(fmt.Sprintf(format, v...)))
+}
+
+func (l *fieldLogger) Error(v ...interface{}) {
+	l.Logger.Error(l.suffix(fmt.Sprint(v...)))
+}
+
+func (l *fieldLogger) Errorf(format string, v ...interface{}) {
+	l.Logger.Error(l.suffix(fmt.Sprintf(format, v...)))
+}
+
+func (l *fieldLogger) Fatal(v ...interface{}) {
+	l.Logger.Fatal(l.suffix(fmt.Sprint(v...)))
+}
+
+func (l *fieldLogger) Fatalf(format string, v ...interface{}) {
+	l.Logger.Fatal(l.suffix(fmt.Sprintf(format, v...)))
+}
+
+func (l *fieldLogger) Panic(v ...interface{}) {
+	l.Logger.Panic(l.suffix(fmt.Sprint(v...)))
+}
+
+func (l *fieldLogger) Panicf(format string, v ...interface{}) {
+	l.Logger.Panic(l.suffix(fmt.Sprintf(format, v...)))
+}
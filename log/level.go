@@ -0,0 +1,237 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Level is the severity of a log entry.
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+	LevelPanic
+)
+
+var levelNames = []string{"debug", "info", "warn", "error", "fatal", "panic"}
+
+func (lvl Level) String() string {
+	if lvl < LevelDebug || lvl > LevelPanic {
+		return fmt.Sprintf("level(%d)", int32(lvl))
+	}
+	return levelNames[lvl]
+}
+
+// ParseLevel converts a level name like "warn" or "WARNING" to a Level.
+func ParseLevel(s string) (Level, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "warning" {
+		return LevelWarn, nil
+	}
+	for i, name := range levelNames {
+		if name == s {
+			return Level(i), nil
+		}
+	}
+	return LevelDebug, fmt.Errorf("log: unknown level %q", s)
+}
+
+func (lvl Level) MarshalText() ([]byte, error) {
+	return []byte(lvl.String()), nil
+}
+
+func (lvl *Level) UnmarshalText(b []byte) (err error) {
+	*lvl, err = ParseLevel(string(b))
+	return
+}
+
+// LevelVar is a concurrency safe level threshold with per module overrides,
+// a module is a package import path and also matches its sub packages.
+// The zero value enables every level.
+type LevelVar struct {
+	level      int32
+	hasModules int32
+	mu         sync.RWMutex
+	modules    map[string]Level
+}
+
+// Level returns the default threshold.
+func (v *LevelVar) Level() Level {
+	return Level(atomic.LoadInt32(&v.level))
+}
+
+// SetLevel changes the default threshold.
+func (v *LevelVar) SetLevel(lvl Level) {
+	atomic.StoreInt32(&v.level, int32(lvl))
+}
+
+// SetModuleLevel overrides the threshold for a module like "github.com/ti/goutil/sqlsearch".
+func (v *LevelVar) SetModuleLevel(module string, lvl Level) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.modules == nil {
+		v.modules = make(map[string]Level)
+	}
+	v.modules[strings.TrimSuffix(module, "/")] = lvl
+	atomic.StoreInt32(&v.hasModules, 1)
+}
+
+// ResetModuleLevel removes the override of a module.
+func (v *LevelVar) ResetModuleLevel(module string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.modules, strings.TrimSuffix(module, "/"))
+	if len(v.modules) == 0 {
+		atomic.StoreInt32(&v.hasModules, 0)
+	}
+}
+
+// ModuleLevel returns the threshold in effect for a module,
+// the longest matching override wins.
+func (v *LevelVar) ModuleLevel(module string) Level {
+	if atomic.LoadInt32(&v.hasModules) == 0 {
+		return v.Level()
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for m := module; m != ""; {
+		if lvl, ok := v.modules[m]; ok {
+			return lvl
+		}
+		i := strings.LastIndexByte(m, '/')
+		if i == -1 {
+			break
+		}
+		m = m[:i]
+	}
+	return v.Level()
+}
+
+// Enabled reports whether lvl passes the threshold of a module.
+func (v *LevelVar) Enabled(module string, lvl Level) bool {
+	return lvl >= v.ModuleLevel(module)
+}
+
+// minLevel returns the lowest threshold of the default and the module overrides,
+// entries below it are disabled in every module.
+func (v *LevelVar) minLevel() Level {
+	lvl := v.Level()
+	if atomic.LoadInt32(&v.hasModules) == 0 {
+		return lvl
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, m := range v.modules {
+		if m < lvl {
+			lvl = m
+		}
+	}
+	return lvl
+}
+
+// enabled is like Enabled for the module of the caller,
+// calldepth is counted the same way as in log.Logger.Output.
+func (v *LevelVar) enabled(calldepth int, lvl Level) bool {
+	if atomic.LoadInt32(&v.hasModules) == 0 {
+		return lvl >= v.Level()
+	}
+	return v.Enabled(callerModule(calldepth), lvl)
+}
+
+type levelState struct {
+	Level   Level            `json:"level"`
+	Modules map[string]Level `json:"modules,omitempty"`
+}
+
+type levelRequest struct {
+	Level  string `json:"level"`
+	Module string `json:"module"`
+}
+
+// ServeHTTP reports the levels as JSON on GET and changes them on PUT or POST.
+// The request is a JSON object or form with "level" and an optional "module",
+// an empty level removes the override of the module.
+//
+//	curl -X PUT -d '{"level":"debug","module":"github.com/ti/goutil/sqlsearch"}' localhost:8080/log/level
+func (v *LevelVar) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var req levelRequest
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			req.Level, req.Module = r.FormValue("level"), r.FormValue("module")
+		} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Level == "" {
+			if req.Module == "" {
+				http.Error(w, "log: level is required", http.StatusBadRequest)
+				return
+			}
+			v.ResetModuleLevel(req.Module)
+			break
+		}
+		lvl, err := ParseLevel(req.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Module != "" {
+			v.SetModuleLevel(req.Module, lvl)
+		} else {
+			v.SetLevel(lvl)
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	state := levelState{Level: v.Level()}
+	v.mu.RLock()
+	if len(v.modules) > 0 {
+		state.Modules = make(map[string]Level, len(v.modules))
+		for m, lvl := range v.modules {
+			state.Modules[m] = lvl
+		}
+	}
+	v.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// callerModule returns the package path of the function skip frames above its caller.
+func callerModule(skip int) string {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return ""
+	}
+	return pcModule(pcs[0])
+}
+
+// pcModule returns the package of the function at pc, as returned by runtime.Callers.
+func pcModule(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return packageOf(frame.Function)
+}
+
+// packageOf extracts "github.com/a/b" from "github.com/a/b.(*T).Method".
+func packageOf(funcName string) string {
+	slash := strings.LastIndexByte(funcName, '/')
+	if dot := strings.IndexByte(funcName[slash+1:], '.'); dot != -1 {
+		return funcName[:slash+1+dot]
+	}
+	return funcName
+}
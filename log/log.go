@@ -2,17 +2,25 @@ package log
 
 import (
 	"log"
-	"os"
+	"net/http"
 )
 
+// calldepth of the code calling a Logger method, counted the same way as in log.Logger.Output.
 const (
-	calldepth = 3
+	calldepth = 2
 )
 
 
-var stdLog = log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile)
+var stdLog = log.New(out, "", log.LstdFlags|log.Lshortfile)
 
-var l Logger = &defaultLogger{stdLog,}
+var levels = &LevelVar{}
+
+var std = newDefaultLogger(stdLog)
+
+var l Logger = std
+
+// pl is l reporting the callers of the package functions instead of the functions themselves.
+var pl = AddCallerSkip(l, 1)
 
 type Logger interface {
 	Log(keyvals ...interface{})
@@ -38,50 +46,117 @@ type Logger interface {
 
 func SetLogger(logger Logger) {
 	l = logger
+	pl = AddCallerSkip(logger, 1)
+}
+
+// AddCallerSkip returns logger reporting the caller n frames above the code calling it,
+// for helpers logging on behalf of their callers. Loggers not supporting it are returned as is.
+//
+//	var logger = log.AddCallerSkip(log.NewDefaultLogger(os.Stderr), 1)
+//
+//	func logRequest(r *http.Request) {
+//		logger.Info(r.Method, r.URL) // reports the caller of logRequest
+//	}
+func AddCallerSkip(logger Logger, n int) Logger {
+	if cs, ok := logger.(interface{ AddCallerSkip(n int) Logger }); ok {
+		return cs.AddCallerSkip(n)
+	}
+	return logger
+}
+
+// SetEncoder selects the output format of the default logger, such as &JSONEncoder{}.
+// It is safe to call while logging, and loggers derived from the default logger
+// by With or AddCallerSkip switch to enc too.
+func SetEncoder(enc Encoder) {
+	std.encoder.Store(enc)
+}
+
+// SetLevel sets the package level threshold, entries below it are not written.
+func SetLevel(lvl Level) {
+	levels.SetLevel(lvl)
+}
+
+// GetLevel returns the package level threshold.
+func GetLevel() Level {
+	return levels.Level()
+}
+
+// SetModuleLevel overrides the package level threshold for a module (package import path) and its sub packages.
+func SetModuleLevel(module string, lvl Level) {
+	levels.SetModuleLevel(module, lvl)
+}
+
+// Enabled reports whether the caller's entries of lvl pass the package level threshold,
+// use it to skip building expensive arguments.
+//
+//    if log.Enabled(log.LevelDebug) {
+//        log.Debug(dump(request))
+//    }
+func Enabled(lvl Level) bool {
+	return levels.enabled(2, lvl)
+}
+
+// LevelHandler returns an http.Handler reading and changing the package level threshold at runtime.
+func LevelHandler() http.Handler {
+	return levels
 }
 
 func Log(keyvals ...interface{}) {
-	l.Log(keyvals...)
+	pl.Log(keyvals...)
 }
 
 func Debug(v ...interface{}) {
-	l.Debug(v)
+	pl.Debug(v...)
 }
 func Debugf(format string, v ...interface{}) {
-	l.Debugf(format, v...)
+	pl.Debugf(format, v...)
 }
 
 func Info(v ...interface{}) {
-	l.Info(v)
+	pl.Info(v...)
 }
 func Infof(format string, v ...interface{}) {
-	l.Infof(format, v...)
+	pl.Infof(format, v...)
 }
 
 func Warn(v ...interface{}) {
-	l.Warn(v)
+	pl.Warn(v...)
 }
 func Warnf(format string, v ...interface{}) {
-	l.Warnf(format, v...)
+	pl.Warnf(format, v...)
 }
 
 func Error(v ...interface{}) {
-	l.Error(v)
+	pl.Error(v...)
 }
 func Errorf(format string, v ...interface{}) {
-	l.Errorf(format, v...)
+	pl.Errorf(format, v...)
 }
 
 func Fatal(v ...interface{}) {
-	l.Fatal(v)
+	pl.Fatal(v...)
 }
 func Fatalf(format string, v ...interface{}) {
-	l.Fatalf(format, v...)
+	pl.Fatalf(format, v...)
 }
 
 func Panic(v ...interface{}) {
-	l.Panic(v)
+	pl.Panic(v...)
 }
 func Panicf(format string, v ...interface{}) {
-	l.Panicf(format, v...)
-}
\ No newline at end of file
+	pl.Panicf(format, v...)
+}
+
+// logAt writes msg with the method of logger matching lvl, Fatal and Panic levels are written as Error.
+func logAt(logger Logger, lvl Level, msg string) {
+	switch {
+	case lvl <= LevelDebug:
+		logger.Debug(msg)
+	case lvl == LevelInfo:
+		logger.Info(msg)
+	case lvl == LevelWarn:
+		logger.Warn(msg)
+	default:
+		logger.Error(msg)
+	}
+}
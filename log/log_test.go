@@ -0,0 +1,208 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	stdlog "log"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	v := &LevelVar{}
+	logger := NewDefaultLogger(&buf, UseLevel(v))
+	v.SetLevel(LevelWarn)
+	logger.Debug("debug line")
+	logger.Infof("info %s", "line")
+	logger.Warn("warn line")
+	if out := buf.String(); strings.Contains(out, "debug line") || strings.Contains(out, "info line") || !strings.Contains(out, "warn line") {
+		t.Fatal("level threshold is not respected:", out)
+	}
+	if logger.Enabled(LevelInfo) || !logger.Enabled(LevelError) {
+		t.Fatal("Enabled does not follow the threshold")
+	}
+
+	v.SetModuleLevel("github.com/ti/goutil", LevelDebug)
+	v.SetModuleLevel("github.com/ti/goutil/sqlsearch", LevelError)
+	if !logger.Enabled(LevelDebug) {
+		t.Fatal("module override is not applied to", callerModule(0))
+	}
+	if v.Enabled("github.com/ti/goutil/sqlsearch/test", LevelWarn) {
+		t.Fatal("the longest module override should win")
+	}
+	v.ResetModuleLevel("github.com/ti/goutil")
+	if logger.Enabled(LevelDebug) {
+		t.Fatal("module override is not reset")
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	v := &LevelVar{}
+	r := httptest.NewRequest("PUT", "/", strings.NewReader(`{"level":"error"}`))
+	w := httptest.NewRecorder()
+	v.ServeHTTP(w, r)
+	if v.Level() != LevelError || !strings.Contains(w.Body.String(), `"level":"error"`) {
+		t.Fatal("level is not changed:", w.Code, w.Body.String())
+	}
+	r = httptest.NewRequest("PUT", "/", strings.NewReader(`{"level":"loud"}`))
+	w = httptest.NewRecorder()
+	v.ServeHTTP(w, r)
+	if w.Code != 400 {
+		t.Fatal("unknown level should be rejected")
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewDefaultLogger(&buf)
+	logger.Infof("hello %s", "world")
+	logger.Log("user", 1, "name", "a b")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], ": INFO: hello world") || !strings.HasSuffix(lines[1], `: LOG: user=1 name="a b"`) {
+		t.Fatal("unexpected text output:", buf.String())
+	}
+
+	tm := time.Date(2018, 9, 18, 19, 0, 0, 0, time.UTC)
+	e := &Entry{Time: tm, Level: LevelWarn, File: "/src/main.go", Line: 12, Message: "say \"hi\"", Fields: []interface{}{"user", 1, "err", errors.New("x=y"), "odd"}}
+	for enc, expect := range map[Encoder]string{
+		&JSONEncoder{}:   `{"ts":"2018-09-18T19:00:00Z","level":"warn","caller":"main.go:12","msg":"say \"hi\"","user":1,"err":"x=y","odd":null}` + "\n",
+		&LogfmtEncoder{}: `ts=2018-09-18T19:00:00Z level=warn caller=main.go:12 msg="say \"hi\"" user=1 err="x=y" odd=null` + "\n",
+	} {
+		buf.Reset()
+		if err := enc.Encode(&buf, e); err != nil || buf.String() != expect {
+			t.Fatal("encoded as", buf.String(), "but expect", expect, err)
+		}
+	}
+}
+
+func TestEncoderNil(t *testing.T) {
+	var buf bytes.Buffer
+	var u *url.URL
+	var err *os.PathError
+	for _, enc := range []Encoder{&TextEncoder{}, &JSONEncoder{}, &LogfmtEncoder{}} {
+		buf.Reset()
+		NewDefaultLogger(&buf, UseEncoder(enc)).Log("url", u, "err", err)
+		if out := buf.String(); strings.Count(out, "nil") != 2 {
+			t.Fatalf("%T: typed nils are not written as <nil>: %s", enc, out)
+		}
+	}
+}
+
+func TestFlagsPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewDefaultLogger(&buf, UseLevel(&LevelVar{}))
+	logger.SetPrefix("PFX ")
+	logger.SetFlags(0)
+	logger.Info("hello")
+	logger.SetFlags(stdlog.Lmsgprefix)
+	logger.Info("again")
+	if out := buf.String(); out != "PFX INFO: hello\nPFX INFO: again\n" {
+		t.Fatalf("flags and prefix are ignored: %q", out)
+	}
+}
+
+func TestSetEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	saved, savedOut := std.encoder.Load(), std.Writer()
+	defer func() {
+		SetEncoder(saved)
+		std.SetOutput(savedOut)
+	}()
+	std.SetOutput(&buf)
+	child := std.With("user", 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			child.Info("tick")
+		}
+	}()
+	SetEncoder(&JSONEncoder{})
+	<-done
+	child.Info("last")
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); !strings.HasPrefix(lines[len(lines)-1], "{") {
+		t.Fatal("child loggers keep the old encoder:", lines[len(lines)-1])
+	}
+}
+
+func TestWith(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewDefaultLogger(&buf, UseEncoder(&LogfmtEncoder{}))
+	child := logger.With("service", "api")
+	child.(FieldLogger).With("user", 7).Info("login")
+	child.Log("event", "ping")
+	logger.Info("plain")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], "msg=login service=api user=7") ||
+		!strings.HasSuffix(lines[1], "service=api event=ping") || !strings.HasSuffix(lines[2], "msg=plain") {
+		t.Fatal("unexpected fields:", buf.String())
+	}
+
+	buf.Reset()
+	ctx := NewContext(context.Background(), "request_id", "r1")
+	WithFields(&fieldLogger{Logger: logger}, FieldsFromContext(ctx)...).Warnf("slow %dms", 300)
+	if !strings.HasSuffix(strings.TrimSpace(buf.String()), `msg="slow 300ms request_id=r1"`) {
+		t.Fatal("fields are not appended to messages:", buf.String())
+	}
+}
+
+func TestCaller(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewDefaultLogger(&buf, UseEncoder(&LogfmtEncoder{}), AddStacktrace(LevelError))
+	saved := l
+	SetLogger(logger)
+	defer SetLogger(saved)
+
+	logger.Info("direct")
+	Info("facade ", 1)
+	WithFields(&fieldLogger{Logger: AddCallerSkip(logger, 1)}, "k", "v").Warn("wrapped")
+	func() { AddCallerSkip(logger, 1).Info("helper") }()
+	Errorf("failed %d", 2)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatal("unexpected output:", buf.String())
+	}
+	for i, msg := range []string{"msg=direct", `msg="facade 1"`, `msg="wrapped k=v"`, "msg=helper", `msg="failed 2"`} {
+		if !strings.Contains(lines[i], "caller=log_test.go:") || !strings.Contains(lines[i], msg) {
+			t.Fatal("wrong caller or message:", lines[i])
+		}
+	}
+	if strings.Contains(lines[0], "stack=") || !strings.Contains(lines[4], "stack=\"github.com/ti/goutil/log.TestCaller") {
+		t.Fatal("stack trace is not attached to errors only:", buf.String())
+	}
+
+	buf.Reset()
+	defer func() {
+		if r := recover(); r != "boom 3" || !strings.Contains(buf.String(), `level=panic caller=log_test.go:`) {
+			t.Fatal("unexpected panic:", r, buf.String())
+		}
+	}()
+	Panic("boom ", 3)
+}
+
+func TestExit(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []string
+	tm := time.Date(2018, 9, 18, 19, 0, 0, 0, time.UTC)
+	logger := NewDefaultLogger(&buf, UseLevel(&LevelVar{}), UseEncoder(&LogfmtEncoder{}),
+		UseClock(func() time.Time { return tm }),
+		UseExit(func(code int) { calls = append(calls, "exit") }))
+
+	saved := exitHooks
+	defer func() { exitHooks = saved }()
+	RegisterExitHook(func() { calls = append(calls, "first") })
+	RegisterExitHook(func() { calls = append(calls, "second") })
+	logger.Fatalf("stop %d", 1)
+	if strings.Join(calls, ",") != "second,first,exit" {
+		t.Fatal("unexpected exit sequence:", calls)
+	}
+	if !strings.HasPrefix(buf.String(), "ts=2018-09-18T19:00:00Z level=fatal caller=log_test.go:") {
+		t.Fatal("clock is not used:", buf.String())
+	}
+}
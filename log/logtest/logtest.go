@@ -0,0 +1,167 @@
+// Package logtest provides loggers for tests: a Recorder keeping entries in memory
+// and a logger writing through testing.TB, so tests neither share nor grep the global output.
+package logtest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	stdlog "log"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ti/goutil/log"
+)
+
+// Recorder is a log.Logger keeping every entry in memory, it is safe for concurrent use.
+// Child loggers created by With record into the same Recorder.
+//
+//	rec := logtest.NewRecorder()
+//	handler := NewHandler(rec)
+//	...
+//	rec.AssertLogged(t, log.LevelWarn, "slow request")
+type Recorder struct {
+	log.Logger
+	rec *recording
+}
+
+type recording struct {
+	mu      sync.Mutex
+	entries []log.Entry
+	exits   []int
+}
+
+// Encode records a copy of e, nothing is written to the output.
+func (r *recording) Encode(buf *bytes.Buffer, e *log.Entry) error {
+	entry := *e
+	entry.Fields = append([]interface{}(nil), e.Fields...)
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *recording) exit(code int) {
+	r.mu.Lock()
+	r.exits = append(r.exits, code)
+	r.mu.Unlock()
+}
+
+// NewRecorder returns a Recorder logging every level, opts are applied after the defaults.
+// Fatal records the exit instead of ending the process and skips the global exit hooks, see Exited.
+func NewRecorder(opts ...log.Option) *Recorder {
+	rec := &recording{}
+	levels := &log.LevelVar{}
+	levels.SetLevel(log.LevelDebug)
+	opts = append([]log.Option{log.UseLevel(levels), log.UseEncoder(rec), log.UseExit(rec.exit), log.SkipExitHooks()}, opts...)
+	return &Recorder{Logger: log.NewDefaultLogger(io.Discard, opts...), rec: rec}
+}
+
+// With returns a child logger adding keyvals to every entry recorded by r.
+func (r *Recorder) With(keyvals ...interface{}) log.Logger {
+	return &Recorder{Logger: log.WithFields(r.Logger, keyvals...), rec: r.rec}
+}
+
+func (r *Recorder) AddCallerSkip(n int) log.Logger {
+	return &Recorder{Logger: log.AddCallerSkip(r.Logger, n), rec: r.rec}
+}
+
+// Entries returns a copy of the recorded entries in order.
+func (r *Recorder) Entries() []log.Entry {
+	r.rec.mu.Lock()
+	defer r.rec.mu.Unlock()
+	return append([]log.Entry(nil), r.rec.entries...)
+}
+
+// Exited reports whether Fatal was called and the exit code it used.
+func (r *Recorder) Exited() (code int, ok bool) {
+	r.rec.mu.Lock()
+	defer r.rec.mu.Unlock()
+	if len(r.rec.exits) == 0 {
+		return 0, false
+	}
+	return r.rec.exits[0], true
+}
+
+// Reset drops the recorded entries and exits.
+func (r *Recorder) Reset() {
+	r.rec.mu.Lock()
+	r.rec.entries = nil
+	r.rec.exits = nil
+	r.rec.mu.Unlock()
+}
+
+// Find returns the entries of lvl whose message or fields contain substr.
+func (r *Recorder) Find(lvl log.Level, substr string) []log.Entry {
+	var found []log.Entry
+	for _, e := range r.Entries() {
+		if e.Level == lvl && strings.Contains(format(&e), substr) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// AssertLogged reports a test error unless an entry of lvl contains substr.
+func (r *Recorder) AssertLogged(t testing.TB, lvl log.Level, substr string) bool {
+	t.Helper()
+	if len(r.Find(lvl, substr)) > 0 {
+		return true
+	}
+	t.Errorf("no %s entry contains %q, recorded:\n%s", lvl, substr, r.dump())
+	return false
+}
+
+// AssertNotLogged reports a test error if an entry of lvl contains substr.
+func (r *Recorder) AssertNotLogged(t testing.TB, lvl log.Level, substr string) bool {
+	t.Helper()
+	if len(r.Find(lvl, substr)) == 0 {
+		return true
+	}
+	t.Errorf("unexpected %s entry containing %q, recorded:\n%s", lvl, substr, r.dump())
+	return false
+}
+
+func (r *Recorder) dump() string {
+	var b strings.Builder
+	for _, e := range r.Entries() {
+		fmt.Fprintf(&b, "\t%s %s: %s\n", e.Caller(), e.Level, format(&e))
+	}
+	return b.String()
+}
+
+// format returns the message followed by the fields in key=value form.
+func format(e *log.Entry) string {
+	var buf bytes.Buffer
+	(&log.TextEncoder{}).Encode(&buf, &log.Entry{Level: e.Level, Message: e.Message, Fields: e.Fields})
+	s := strings.TrimSuffix(buf.String(), "\n")
+	if i := strings.Index(s, ": "); i != -1 {
+		s = s[i+2:]
+	}
+	return s
+}
+
+// NewTB returns a logger writing every level through t.Log, so the output is shown
+// with the test that produced it and only when it fails or runs with -v.
+// Fatal marks the test as failed instead of ending the process, the global exit hooks are skipped.
+func NewTB(t testing.TB, opts ...log.Option) log.Logger {
+	levels := &log.LevelVar{}
+	levels.SetLevel(log.LevelDebug)
+	enc := &log.TextEncoder{Flags: stdlog.Lshortfile}
+	fail := func(code int) {
+		t.Errorf("log: Fatal called with exit code %d", code)
+	}
+	opts = append([]log.Option{log.UseLevel(levels), log.UseEncoder(enc), log.UseExit(fail), log.SkipExitHooks()}, opts...)
+	return log.NewDefaultLogger(tbWriter{t}, opts...)
+}
+
+type tbWriter struct {
+	t testing.TB
+}
+
+func (w tbWriter) Write(p []byte) (int, error) {
+	w.t.Helper()
+	w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
@@ -0,0 +1,54 @@
+package logtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ti/goutil/log"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder()
+	rec.With("user", 7).(log.FieldLogger).With("role", "admin").Warnf("slow %dms", 300)
+	rec.Log("event", "ping")
+	rec.Debug("details")
+
+	entries := rec.Entries()
+	if len(entries) != 3 || entries[0].Level != log.LevelWarn || entries[0].Message != "slow 300ms" ||
+		len(entries[0].Fields) != 4 || !strings.HasPrefix(entries[0].Caller(), "logtest_test.go:") {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	rec.AssertLogged(t, log.LevelWarn, "slow 300ms user=7")
+	rec.AssertLogged(t, log.LevelInfo, "event=ping")
+	rec.AssertNotLogged(t, log.LevelError, "slow")
+
+	tb := &fakeTB{TB: t}
+	if rec.AssertLogged(tb, log.LevelInfo, "details") || !tb.failed {
+		t.Fatal("a debug entry should not match the info level")
+	}
+	hooked := false
+	log.RegisterExitHook(func() { hooked = true })
+	rec.Fatalf("stop %d", 1)
+	if code, ok := rec.Exited(); !ok || code != 1 || hooked {
+		t.Fatal("Fatal exit is not recorded or runs the exit hooks")
+	}
+	rec.AssertLogged(t, log.LevelFatal, "stop 1")
+	rec.Reset()
+	if _, ok := rec.Exited(); ok || len(rec.Entries()) != 0 {
+		t.Fatal("entries are not reset")
+	}
+}
+
+func TestTB(t *testing.T) {
+	logger := NewTB(t)
+	logger.Infof("visible with %s", "-v")
+}
+
+type fakeTB struct {
+	testing.TB
+	failed bool
+}
+
+func (tb *fakeTB) Errorf(format string, args ...interface{}) {
+	tb.failed = true
+}
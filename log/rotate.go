@@ -0,0 +1,355 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RotateInterval is the schedule of time based rotation.
+type RotateInterval int
+
+const (
+	RotateNever RotateInterval = iota
+	RotateHourly
+	RotateDaily
+)
+
+const backupTimeFormat = "20060102T150405.000"
+
+// RotateConfig configures a RotateWriter, zero values disable the matching feature.
+type RotateConfig struct {
+	// MaxSize rolls the file over before it grows beyond MaxSize bytes.
+	MaxSize int64
+	// Interval rolls the file over every hour or day.
+	Interval RotateInterval
+	// MaxBackups is the number of rotated files to keep.
+	MaxBackups int
+	// MaxAge removes rotated files older than MaxAge.
+	MaxAge time.Duration
+	// Compress gzips rotated files in the background.
+	Compress bool
+	// FileMode of the log file, 0644 by default.
+	FileMode os.FileMode
+}
+
+// RotateWriter is an io.Writer appending to a file that is rolled over by size or schedule,
+// rotated files are renamed to "name-20060102T150405.000.ext", with a "-1", "-2"... suffix
+// before the extension when the name is taken. It can be passed to SetDefaultOutput.
+// When the file cannot be reopened, the next Write tries again.
+type RotateWriter struct {
+	path string
+	cfg  RotateConfig
+
+	mu     sync.Mutex
+	file   *os.File
+	closed bool
+	size   int64
+	next   time.Time
+
+	mill    chan struct{}
+	done    chan struct{}
+	signals chan os.Signal
+	wg      sync.WaitGroup
+}
+
+// NewRotateWriter opens or creates the file at path, creating its directory when needed.
+func NewRotateWriter(path string, cfg RotateConfig) (*RotateWriter, error) {
+	if cfg.FileMode == 0 {
+		cfg.FileMode = 0644
+	}
+	w := &RotateWriter{
+		path: path,
+		cfg:  cfg,
+		mill: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	w.wg.Add(1)
+	go w.millLoop()
+	w.triggerMill()
+	return w, nil
+}
+
+func (w *RotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, w.cfg.FileMode)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size = f, info.Size()
+	w.next = nextRotation(time.Now(), w.cfg.Interval)
+	return nil
+}
+
+func nextRotation(now time.Time, interval RotateInterval) time.Time {
+	switch interval {
+	case RotateHourly:
+		return time.Date(now.Year(), now.Month(), now.Day(), now.Hour()+1, 0, 0, 0, now.Location())
+	case RotateDaily:
+		return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	}
+	return time.Time{}
+}
+
+// reopen opens the file again if a failed rotation left it closed.
+func (w *RotateWriter) reopen() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.file == nil {
+		return w.open()
+	}
+	return nil
+}
+
+func (w *RotateWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.reopen(); err != nil {
+		return 0, err
+	}
+	if (w.cfg.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.cfg.MaxSize) ||
+		(!w.next.IsZero() && !time.Now().Before(w.next)) {
+		// p still goes to the current file when only the rename failed
+		if err := w.rotate(); err != nil && w.file == nil {
+			return 0, err
+		}
+	}
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate rolls the file over immediately.
+func (w *RotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.reopen(); err != nil {
+		return err
+	}
+	return w.rotate()
+}
+
+// rotate renames the file and opens a new one. If the rename fails the current file
+// is opened again, if the open fails w.file is left nil for the next Write to retry.
+func (w *RotateWriter) rotate() error {
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(w.path, w.backupName(time.Now())); err != nil && !os.IsNotExist(err) {
+		if oerr := w.open(); oerr != nil {
+			return oerr
+		}
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.triggerMill()
+	return nil
+}
+
+// Reopen closes and reopens the file without renaming it, for use after an
+// external tool such as logrotate moved it away.
+func (w *RotateWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+	return w.open()
+}
+
+// ReopenOnSignal calls Reopen whenever one of sigs is received, SIGHUP by default.
+func (w *RotateWriter) ReopenOnSignal(sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.signals != nil {
+		signal.Notify(w.signals, sigs...)
+		return
+	}
+	w.signals = make(chan os.Signal, 1)
+	signal.Notify(w.signals, sigs...)
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		for {
+			select {
+			case <-w.signals:
+				w.Reopen()
+			case <-w.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close closes the file, finishing pending compression and cleanup first.
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return os.ErrClosed
+	}
+	w.closed = true
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	if w.signals != nil {
+		signal.Stop(w.signals)
+	}
+	close(w.done)
+	w.mu.Unlock()
+	w.wg.Wait()
+	w.millOnce()
+	return err
+}
+
+// backupName returns an unused name for a file rotated at t, compressed backups included.
+func (w *RotateWriter) backupName(t time.Time) string {
+	dir, prefix, ext := w.nameParts()
+	stamp := prefix + t.Format(backupTimeFormat)
+	name := filepath.Join(dir, stamp+ext)
+	for i := 1; exists(name) || exists(name+".gz"); i++ {
+		name = filepath.Join(dir, stamp+"-"+strconv.Itoa(i)+ext)
+	}
+	return name
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func (w *RotateWriter) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(w.path)
+	base := filepath.Base(w.path)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+func (w *RotateWriter) triggerMill() {
+	select {
+	case w.mill <- struct{}{}:
+	default:
+	}
+}
+
+func (w *RotateWriter) millLoop() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.mill:
+			w.millOnce()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+	seq  int
+}
+
+// millOnce compresses rotated files and removes the ones exceeding MaxBackups or MaxAge.
+func (w *RotateWriter) millOnce() {
+	if !w.cfg.Compress && w.cfg.MaxBackups == 0 && w.cfg.MaxAge == 0 {
+		return
+	}
+	dir, prefix, ext := w.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var backups []backupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name[len(prefix):], ".gz"), ext)
+		var seq int
+		if i := strings.LastIndexByte(stamp, '-'); i >= 0 {
+			if seq, err = strconv.Atoi(stamp[i+1:]); err != nil {
+				continue
+			}
+			stamp = stamp[:i]
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{filepath.Join(dir, name), t, seq})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].time.Equal(backups[j].time) {
+			return backups[i].time.After(backups[j].time)
+		}
+		return backups[i].seq > backups[j].seq
+	})
+	for i, b := range backups {
+		if (w.cfg.MaxBackups > 0 && i >= w.cfg.MaxBackups) || (w.cfg.MaxAge > 0 && time.Since(b.time) > w.cfg.MaxAge) {
+			os.Remove(b.path)
+			continue
+		}
+		if w.cfg.Compress && !strings.HasSuffix(b.path, ".gz") {
+			if err := compressFile(b.path, w.cfg.FileMode); err == nil {
+				os.Remove(b.path)
+			}
+		}
+	}
+}
+
+func compressFile(path string, mode os.FileMode) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotateWriter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := NewRotateWriter(path, RotateConfig{MaxSize: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"line one\n", "line two\n", "line three\n", "line four\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(path)
+	if string(b) != "line four\n" {
+		t.Fatal("current file holds", string(b))
+	}
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	if len(backups) != 2 {
+		t.Fatal("expect 2 backups but got", backups)
+	}
+	lines := map[string]bool{}
+	for _, name := range backups {
+		b, _ := os.ReadFile(name)
+		lines[string(b)] = true
+	}
+	if !lines["line two\n"] || !lines["line three\n"] {
+		t.Fatal("rotated lines are lost", lines)
+	}
+}
+
+func TestRotateWriterSameName(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := NewRotateWriter(path, RotateConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	now := time.Now()
+	names := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		name := w.backupName(now)
+		if names[name] {
+			t.Fatal("backup name reused", name)
+		}
+		names[name] = true
+		os.WriteFile(name, nil, 0644)
+	}
+	if !names[filepath.Join(dir, "app-"+now.Format(backupTimeFormat)+"-2.log")] {
+		t.Fatal("unexpected backup names", names)
+	}
+}
+
+func TestRotateWriterRetry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "app.log")
+	w, err := NewRotateWriter(path, RotateConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.ReopenOnSignal()
+	// the directory turns into a file, so the log file cannot be opened again
+	os.RemoveAll(filepath.Dir(path))
+	os.WriteFile(filepath.Dir(path), nil, 0644)
+	if err := w.Reopen(); err == nil {
+		t.Fatal("expect an error reopening the file")
+	}
+	if _, err := w.Write([]byte("lost\n")); err == nil || err == os.ErrClosed {
+		t.Fatal("expect the open error", err)
+	}
+	os.Remove(filepath.Dir(path))
+	if _, err := w.Write([]byte("hello\n")); err != nil {
+		t.Fatal("the file is not opened again", err)
+	}
+	w.Close()
+	if b, _ := os.ReadFile(path); string(b) != "hello\n" {
+		t.Fatal("unexpected file", string(b))
+	}
+	if err := w.Close(); err != os.ErrClosed {
+		t.Fatal("expect ErrClosed", err)
+	}
+}
+
+func TestRotateWriterCompress(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := NewRotateWriter(path, RotateConfig{Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("hello\n"))
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-*"))
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".log.gz") {
+		t.Fatal("rotated file is not compressed", backups)
+	}
+}
+
+func TestNextRotation(t *testing.T) {
+	now := time.Date(2018, 9, 18, 19, 30, 0, 0, time.UTC)
+	if next := nextRotation(now, RotateHourly); !next.Equal(time.Date(2018, 9, 18, 20, 0, 0, 0, time.UTC)) {
+		t.Fatal("unexpected hourly rotation", next)
+	}
+	if next := nextRotation(now, RotateDaily); !next.Equal(time.Date(2018, 9, 19, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("unexpected daily rotation", next)
+	}
+}
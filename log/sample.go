@@ -0,0 +1,243 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SampleConfig configures NewSampler.
+type SampleConfig struct {
+	// Interval is the sampling window, one second by default.
+	Interval time.Duration
+	// First entries of each message are logged per Interval, 0 disables sampling.
+	First int
+	// Thereafter every Thereafter-th entry of the message is logged, 0 drops them all.
+	Thereafter int
+	// Dedup collapses consecutive identical messages into one "repeated N times" summary
+	// written when another message arrives or Interval elapses.
+	Dedup bool
+}
+
+// Sampler is a Logger limiting how often the same message reaches the wrapped Logger.
+// Messages are keyed by level and format string, or by the message itself for the
+// non formatting methods. Fatal and Panic are never sampled.
+type Sampler struct {
+	logger Logger
+	state  *sampleState
+}
+
+type sampleKey struct {
+	level Level
+	msg   string
+}
+
+type sampleCounter struct {
+	start time.Time
+	n     int
+}
+
+type sampleState struct {
+	cfg SampleConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	counters map[sampleKey]*sampleCounter
+	swept    time.Time
+
+	last     sampleKey
+	lastMsg  string
+	lastTime time.Time
+	repeated int
+	timer    *time.Timer
+	summary  Logger
+}
+
+// NewSampler returns a Sampler wrapping logger, pass it to SetLogger to sample the package logger.
+func NewSampler(logger Logger, cfg SampleConfig) *Sampler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	return &Sampler{
+		logger: AddCallerSkip(logger, 1),
+		state: &sampleState{
+			cfg:      cfg,
+			now:      time.Now,
+			counters: make(map[sampleKey]*sampleCounter),
+		},
+	}
+}
+
+// With returns a child Sampler sharing the counters of s.
+func (s *Sampler) With(keyvals ...interface{}) Logger {
+	return &Sampler{logger: WithFields(s.logger, keyvals...), state: s.state}
+}
+
+// AddCallerSkip returns a Sampler sharing the counters of s and reporting the caller n frames further up.
+func (s *Sampler) AddCallerSkip(n int) Logger {
+	return &Sampler{logger: AddCallerSkip(s.logger, n), state: s.state}
+}
+
+// Flush writes the pending dedup summary, if any.
+func (s *Sampler) Flush() {
+	st := s.state
+	st.mu.Lock()
+	summary, lvl, msg := st.takeSummary()
+	st.mu.Unlock()
+	if summary != nil {
+		logAt(summary, lvl, msg)
+	}
+}
+
+// allow reports whether an entry is passed on, writing the pending dedup summary
+// first when the entry ends a run of repeats.
+func (s *Sampler) allow(lvl Level, key, msg string) bool {
+	st := s.state
+	k := sampleKey{lvl, key}
+	now := st.now()
+	st.mu.Lock()
+	var summary Logger
+	var summaryLevel Level
+	var summaryMsg string
+	if st.cfg.Dedup {
+		if k == st.last && msg == st.lastMsg && now.Sub(st.lastTime) < st.cfg.Interval {
+			st.repeated++
+			if st.timer == nil {
+				st.timer = time.AfterFunc(st.cfg.Interval-now.Sub(st.lastTime), s.Flush)
+			}
+			st.mu.Unlock()
+			return false
+		}
+		summary, summaryLevel, summaryMsg = st.takeSummary()
+		st.last, st.lastMsg, st.lastTime, st.summary = k, msg, now, s.logger
+	}
+	allowed := true
+	if st.cfg.First > 0 {
+		st.sweep(now)
+		c := st.counters[k]
+		if c == nil || now.Sub(c.start) >= st.cfg.Interval {
+			c = &sampleCounter{start: now}
+			st.counters[k] = c
+		}
+		c.n++
+		if c.n > st.cfg.First {
+			allowed = st.cfg.Thereafter > 0 && (c.n-st.cfg.First)%st.cfg.Thereafter == 0
+		}
+	}
+	st.mu.Unlock()
+	if summary != nil {
+		logAt(summary, summaryLevel, summaryMsg)
+	}
+	return allowed
+}
+
+// sweep removes the counters whose window has elapsed, at most once per Interval,
+// so distinct messages do not pile up. It must be called with mu held.
+func (st *sampleState) sweep(now time.Time) {
+	if now.Sub(st.swept) < st.cfg.Interval {
+		return
+	}
+	for k, c := range st.counters {
+		if now.Sub(c.start) >= st.cfg.Interval {
+			delete(st.counters, k)
+		}
+	}
+	st.swept = now
+}
+
+// takeSummary returns the pending summary and resets it, it must be called with mu held.
+func (st *sampleState) takeSummary() (Logger, Level, string) {
+	if st.timer != nil {
+		st.timer.Stop()
+		st.timer = nil
+	}
+	if st.repeated == 0 || st.summary == nil {
+		return nil, 0, ""
+	}
+	msg := fmt.Sprintf("%s (repeated %d times)", st.lastMsg, st.repeated)
+	st.repeated = 0
+	return st.summary, st.last.level, msg
+}
+
+func (s *Sampler) Log(keyvals ...interface{}) {
+	msg := fmt.Sprint(keyvals...)
+	if s.allow(LevelInfo, msg, msg) {
+		s.logger.Log(keyvals...)
+	}
+}
+
+func (s *Sampler) Debug(v ...interface{}) {
+	msg := fmt.Sprint(v...)
+	if s.allow(LevelDebug, msg, msg) {
+		s.logger.Debug(msg)
+	}
+}
+
+func (s *Sampler) Debugf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	if s.allow(LevelDebug, format, msg) {
+		s.logger.Debug(msg)
+	}
+}
+
+func (s *Sampler) Info(v ...interface{}) {
+	msg := fmt.Sprint(v...)
+	if s.allow(LevelInfo, msg, msg) {
+		s.logger.Info(msg)
+	}
+}
+
+func (s *Sampler) Infof(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	if s.allow(LevelInfo, format, msg) {
+		s.logger.Info(msg)
+	}
+}
+
+func (s *Sampler) Warn(v ...interface{}) {
+	msg := fmt.Sprint(v...)
+	if s.allow(LevelWarn, msg, msg) {
+		s.logger.Warn(msg)
+	}
+}
+
+func (s *Sampler) Warnf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	if s.allow(LevelWarn, format, msg) {
+		s.logger.Warn(msg)
+	}
+}
+
+func (s *Sampler) Error(v ...interface{}) {
+	msg := fmt.Sprint(v...)
+	if s.allow(LevelError, msg, msg) {
+		s.logger.Error(msg)
+	}
+}
+
+func (s *Sampler) Errorf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	if s.allow(LevelError, format, msg) {
+		s.logger.Error(msg)
+	}
+}
+
+func (s *Sampler) Fatal(v ...interface{}) {
+	s.Flush()
+	s.logger.Fatal(v...)
+}
+
+func (s *Sampler) Fatalf(format string, v ...interface{}) {
+	s.Flush()
+	s.logger.Fatalf(format, v...)
+}
+
+func (s *Sampler) Panic(v ...interface{}) {
+	s.Flush()
+	s.logger.Panic(v...)
+}
+
+func (s *Sampler) Panicf(format string, v ...interface{}) {
+	s.Flush()
+	s.logger.Panicf(format, v...)
+}
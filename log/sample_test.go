@@ -0,0 +1,53 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2018, 9, 18, 19, 0, 0, 0, time.UTC)
+	s := NewSampler(NewDefaultLogger(&buf, UseEncoder(&LogfmtEncoder{})), SampleConfig{First: 2, Thereafter: 3})
+	s.state.now = func() time.Time { return now }
+	for i := 0; i < 10; i++ {
+		s.Errorf("request %d failed", i)
+	}
+	s.Info("other")
+	if got := strings.Count(buf.String(), "failed"); got != 4 {
+		t.Fatal("expect 2 first and 2 sampled lines but got", got, buf.String())
+	}
+	now = now.Add(time.Second)
+	buf.Reset()
+	s.Errorf("request %d failed", 10)
+	if !strings.Contains(buf.String(), "request 10 failed") {
+		t.Fatal("sampling is not reset after the interval")
+	}
+
+	for i := 0; i < 1000; i++ {
+		if i%100 == 0 {
+			now = now.Add(time.Second)
+		}
+		s.Error("user ", i, " failed")
+	}
+	if n := len(s.state.counters); n > 200 {
+		t.Fatal("counters of elapsed windows are kept:", n)
+	}
+}
+
+func TestSamplerDedup(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2018, 9, 18, 19, 0, 0, 0, time.UTC)
+	s := NewSampler(NewDefaultLogger(&buf, UseEncoder(&LogfmtEncoder{})), SampleConfig{Dedup: true, Interval: time.Hour})
+	s.state.now = func() time.Time { return now }
+	for i := 0; i < 533; i++ {
+		s.Error("disk full")
+	}
+	s.Warn("recovered")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], `level=error`) || !strings.Contains(lines[1], `msg="disk full (repeated 532 times)"`) || !strings.Contains(lines[2], "recovered") {
+		t.Fatal("unexpected dedup output:", buf.String())
+	}
+}
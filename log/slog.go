@@ -0,0 +1,227 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// Levels used for Fatal and Panic entries written to a *slog.Logger.
+const (
+	SlogLevelFatal = slog.Level(12)
+	SlogLevelPanic = slog.Level(16)
+)
+
+// SlogLevel converts a Level to the matching slog level.
+func SlogLevel(lvl Level) slog.Level {
+	switch lvl {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelInfo:
+		return slog.LevelInfo
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	case LevelFatal:
+		return SlogLevelFatal
+	}
+	return SlogLevelPanic
+}
+
+// FromSlogLevel converts a slog level to the closest Level.
+func FromSlogLevel(lvl slog.Level) Level {
+	switch {
+	case lvl < slog.LevelInfo:
+		return LevelDebug
+	case lvl < slog.LevelWarn:
+		return LevelInfo
+	case lvl < slog.LevelError:
+		return LevelWarn
+	case lvl < SlogLevelFatal:
+		return LevelError
+	case lvl < SlogLevelPanic:
+		return LevelFatal
+	}
+	return LevelPanic
+}
+
+// NewSlogHandler returns a slog.Handler writing records to logger, attributes become fields.
+// Records at Fatal level and above are written as errors, they never exit or panic.
+//
+//	slog.SetDefault(slog.New(log.NewSlogHandler(logger)))
+func NewSlogHandler(logger Logger) slog.Handler {
+	return &slogHandler{logger: logger}
+}
+
+type slogHandler struct {
+	logger Logger
+	attrs  []interface{}
+	group  string
+}
+
+// Enabled reports whether lvl may pass the level threshold of the logger, the caller
+// is unknown here so module overrides are checked again by Handle.
+func (h *slogHandler) Enabled(ctx context.Context, lvl slog.Level) bool {
+	switch l := h.logger.(type) {
+	case *defaultLogger:
+		level := FromSlogLevel(lvl)
+		if level > LevelError {
+			level = LevelError
+		}
+		return level >= l.levels.minLevel()
+	case *slogLogger:
+		return l.sl.Enabled(ctx, lvl)
+	}
+	return true
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ctxFields := FieldsFromContext(ctx)
+	keyvals := make([]interface{}, 0, len(h.attrs)+len(ctxFields)+2*r.NumAttrs())
+	keyvals = append(append(keyvals, h.attrs...), ctxFields...)
+	r.Attrs(func(a slog.Attr) bool {
+		keyvals = appendAttr(keyvals, h.group, a)
+		return true
+	})
+	lvl := FromSlogLevel(r.Level)
+	if lvl > LevelError {
+		lvl = LevelError
+	}
+	if dl, ok := h.logger.(*defaultLogger); ok {
+		dl.logPC(r.PC, lvl, r.Message, keyvals)
+		return nil
+	}
+	logAt(WithFields(h.logger, keyvals...), lvl, r.Message)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	child := *h
+	child.attrs = make([]interface{}, 0, len(h.attrs)+2*len(attrs))
+	child.attrs = append(child.attrs, h.attrs...)
+	for _, a := range attrs {
+		child.attrs = appendAttr(child.attrs, h.group, a)
+	}
+	return &child
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	child := *h
+	child.group = h.group + name + "."
+	return &child
+}
+
+// appendAttr flattens groups into dotted keys and appends them to keyvals in place,
+// so keyvals must not be shared.
+func appendAttr(keyvals []interface{}, prefix string, a slog.Attr) []interface{} {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			keyvals = appendAttr(keyvals, prefix, ga)
+		}
+		return keyvals
+	}
+	if a.Key == "" {
+		return keyvals
+	}
+	return append(keyvals, prefix+a.Key, v.Any())
+}
+
+// NewSlogLogger returns a Logger writing to sl, Log key value pairs become attributes.
+// Fatal exits and Panic panics after writing, like the default logger.
+func NewSlogLogger(sl *slog.Logger) Logger {
+	return &slogLogger{sl: sl}
+}
+
+type slogLogger struct {
+	sl   *slog.Logger
+	skip int
+}
+
+func (l *slogLogger) With(keyvals ...interface{}) Logger {
+	return &slogLogger{sl: l.sl.With(keyvals...), skip: l.skip}
+}
+
+func (l *slogLogger) AddCallerSkip(n int) Logger {
+	return &slogLogger{sl: l.sl, skip: l.skip + n}
+}
+
+// log writes a record reporting the caller of the Logger method as its source.
+func (l *slogLogger) log(lvl slog.Level, msg string, keyvals []interface{}) {
+	ctx := context.Background()
+	if !l.sl.Enabled(ctx, lvl) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3+l.skip, pcs[:])
+	r := slog.NewRecord(time.Now(), lvl, msg, pcs[0])
+	r.Add(keyvals...)
+	l.sl.Handler().Handle(ctx, r)
+}
+
+func (l *slogLogger) Log(keyvals ...interface{}) {
+	l.log(slog.LevelInfo, "", keyvals)
+}
+
+func (l *slogLogger) Debug(v ...interface{}) {
+	l.log(slog.LevelDebug, fmt.Sprint(v...), nil)
+}
+
+func (l *slogLogger) Debugf(format string, v ...interface{}) {
+	l.log(slog.LevelDebug, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *slogLogger) Info(v ...interface{}) {
+	l.log(slog.LevelInfo, fmt.Sprint(v...), nil)
+}
+
+func (l *slogLogger) Infof(format string, v ...interface{}) {
+	l.log(slog.LevelInfo, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *slogLogger) Warn(v ...interface{}) {
+	l.log(slog.LevelWarn, fmt.Sprint(v...), nil)
+}
+
+func (l *slogLogger) Warnf(format string, v ...interface{}) {
+	l.log(slog.LevelWarn, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *slogLogger) Error(v ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprint(v...), nil)
+}
+
+func (l *slogLogger) Errorf(format string, v ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *slogLogger) Fatal(v ...interface{}) {
+	l.log(SlogLevelFatal, fmt.Sprint(v...), nil)
+	exit(nil, nil, false, 1)
+}
+
+func (l *slogLogger) Fatalf(format string, v ...interface{}) {
+	l.log(SlogLevelFatal, fmt.Sprintf(format, v...), nil)
+	exit(nil, nil, false, 1)
+}
+
+func (l *slogLogger) Panic(v ...interface{}) {
+	msg := fmt.Sprint(v...)
+	l.log(SlogLevelPanic, msg, nil)
+	panic(msg)
+}
+
+func (l *slogLogger) Panicf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	l.log(SlogLevelPanic, msg, nil)
+	panic(msg)
+}
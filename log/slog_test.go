@@ -0,0 +1,54 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	sl := slog.New(NewSlogHandler(NewDefaultLogger(&buf, UseEncoder(&LogfmtEncoder{}))))
+	sl.With("service", "api").WithGroup("req").Warn("slow", "ms", 300, slog.Group("user", "id", 7))
+	if out := buf.String(); !strings.Contains(out, "level=warn caller=slog_test.go:") || !strings.HasSuffix(out, "msg=slow service=api req.ms=300 req.user.id=7\n") {
+		t.Fatal("unexpected output:", out)
+	}
+
+	levels := &LevelVar{}
+	levels.SetLevel(LevelWarn)
+	h := NewSlogHandler(NewDefaultLogger(&buf, UseLevel(levels)))
+	if h.Enabled(context.Background(), slog.LevelInfo) || !h.Enabled(context.Background(), SlogLevelFatal) {
+		t.Fatal("the logger level is not checked")
+	}
+	levels.SetModuleLevel("example.com/app", LevelDebug)
+	if !h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("module levels are not checked")
+	}
+
+	buf.Reset()
+	sl = slog.New(NewSlogHandler(NewSampler(NewDefaultLogger(&buf, UseLevel(&LevelVar{}), UseEncoder(&LogfmtEncoder{})), SampleConfig{}))).With("service", "api")
+	sl.Warn("", "disk", "full")
+	sl.Error("failed", "code", 1)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "level=warn") || !strings.HasSuffix(lines[0], "service=api disk=full") ||
+		!strings.Contains(lines[1], "level=error") || strings.Contains(lines[1], "disk") {
+		t.Fatal("unexpected output:", buf.String())
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{AddSource: true, Level: slog.LevelDebug})))
+	logger.(FieldLogger).With("service", "api").Debugf("hello %s", "world")
+	logger.Log("event", "ping")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `level=DEBUG source=`) || !strings.Contains(lines[0], "slog_test.go") ||
+		!strings.Contains(lines[0], `msg="hello world" service=api`) || !strings.Contains(lines[1], `msg="" event=ping`) {
+		t.Fatal("unexpected output:", buf.String())
+	}
+	if SlogLevel(LevelFatal) != SlogLevelFatal || FromSlogLevel(slog.LevelWarn+1) != LevelWarn {
+		t.Fatal("unexpected level mapping")
+	}
+}
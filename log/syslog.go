@@ -0,0 +1,201 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog facilities, see RFC 5424 section 6.2.1.
+const (
+	FacilityKern   = 0
+	FacilityUser   = 1
+	FacilityDaemon = 3
+	FacilityAuth   = 4
+	FacilityLocal0 = 16
+	FacilityLocal1 = 17
+	FacilityLocal2 = 18
+	FacilityLocal3 = 19
+	FacilityLocal4 = 20
+	FacilityLocal5 = 21
+	FacilityLocal6 = 22
+	FacilityLocal7 = 23
+)
+
+// SyslogEncoder writes entries as RFC 5424 syslog messages without a trailing newline,
+// one message per datagram. Fields follow the message in key=value form,
+// the stack trace added by AddStacktrace follows on the next lines.
+//
+//	<11>1 2018-09-18T19:00:00.000000+08:00 host app 42 - - disk full path=/data
+type SyslogEncoder struct {
+	Facility int
+	// AppName defaults to the program name.
+	AppName string
+	// Hostname defaults to os.Hostname.
+	Hostname string
+}
+
+func (enc *SyslogEncoder) Encode(buf *bytes.Buffer, e *Entry) error {
+	buf.WriteByte('<')
+	buf.WriteString(strconv.Itoa(enc.Facility*8 + syslogSeverity(e.Level)))
+	buf.WriteString(">1 ")
+	buf.WriteString(e.Time.Format("2006-01-02T15:04:05.000000Z07:00"))
+	buf.WriteByte(' ')
+	buf.WriteString(syslogField(enc.Hostname, hostname, 255))
+	buf.WriteByte(' ')
+	buf.WriteString(syslogField(enc.AppName, appName, 48))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.Itoa(os.Getpid()))
+	buf.WriteString(" - - ")
+	buf.WriteString(e.Message)
+	for i := 0; i < len(e.Fields); i += 2 {
+		if i > 0 || e.Message != "" {
+			buf.WriteByte(' ')
+		}
+		writeLogfmtPair(buf, e.Fields, i)
+	}
+	if e.Stack != "" {
+		buf.WriteByte('\n')
+		buf.WriteString(strings.TrimRight(e.Stack, "\n"))
+	}
+	return nil
+}
+
+var hostname, _ = os.Hostname()
+
+var appName = filepath.Base(os.Args[0])
+
+// syslogField returns v or def, cut to max printable ASCII characters, "-" when empty.
+func syslogField(v, def string, max int) string {
+	if v == "" {
+		v = def
+	}
+	b := make([]byte, 0, len(v))
+	for i := 0; i < len(v) && len(b) < max; i++ {
+		if c := v[i]; c > ' ' && c < 0x7f {
+			b = append(b, c)
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
+
+func syslogSeverity(lvl Level) int {
+	switch lvl {
+	case LevelDebug:
+		return 7
+	case LevelInfo:
+		return 6
+	case LevelWarn:
+		return 4
+	case LevelError:
+		return 3
+	case LevelFatal:
+		return 2
+	}
+	return 1
+}
+
+// DialSyslog connects to a syslog daemon over "udp", "unixgram", "tcp" or "unix",
+// an empty network tries the usual local unix sockets. Messages sent over the
+// stream networks "tcp" and "unix" are framed with their length, see RFC 6587 section 3.4.1.
+// A write failing because the connection dropped dials again and retries once.
+func DialSyslog(network, addr string) (io.Writer, error) {
+	w := &syslogWriter{dial: func() (net.Conn, error) {
+		return net.DialTimeout(network, addr, 5*time.Second)
+	}}
+	switch network {
+	case "":
+		w.dial = dialLocalSyslog
+	case "tcp", "tcp4", "tcp6", "unix":
+		w.framed = true
+	}
+	conn, err := w.dial()
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	return w, nil
+}
+
+func dialLocalSyslog() (net.Conn, error) {
+	for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+		if conn, err := net.Dial("unixgram", path); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, errors.New("log: no local syslog socket found")
+}
+
+// NewSyslogSink dials a syslog daemon and returns a Sink writing entries of lvl and above to it.
+func NewSyslogSink(network, addr, appName string, facility int, lvl Level) (Sink, error) {
+	w, err := DialSyslog(network, addr)
+	if err != nil {
+		return Sink{}, err
+	}
+	return Sink{Writer: w, Encoder: &SyslogEncoder{Facility: facility, AppName: appName}, Level: lvl}, nil
+}
+
+// syslogWriter sends every Write as one message, prefixed with its length in bytes
+// when framed so the receiver of a stream can split it into messages.
+type syslogWriter struct {
+	dial   func() (net.Conn, error)
+	framed bool
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	msg := p
+	if w.framed {
+		msg = bytes.TrimRight(p, "\n")
+		frame := make([]byte, 0, len(msg)+8)
+		frame = strconv.AppendInt(frame, int64(len(msg)), 10)
+		frame = append(frame, ' ')
+		msg = append(frame, msg...)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, net.ErrClosed
+	}
+	var err error
+	for retry := 0; retry < 2; retry++ {
+		if w.conn == nil {
+			if w.conn, err = w.dial(); err != nil {
+				w.conn = nil
+				return 0, err
+			}
+		}
+		if _, err = w.conn.Write(msg); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+// Close closes the connection, later writes fail with net.ErrClosed.
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
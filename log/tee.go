@@ -0,0 +1,33 @@
+package log
+
+import (
+	"io"
+	"log"
+)
+
+// Sink is a destination of a Tee logger with its own format and minimum level.
+type Sink struct {
+	Writer  io.Writer
+	Encoder Encoder
+	Level   Level
+}
+
+// Tee returns a Logger writing every entry to each sink whose Level it reaches,
+// sinks without an Encoder use a TextEncoder. Entries must pass the package level
+// threshold first, so SetLevel, SetModuleLevel and LevelHandler apply to the sinks too.
+//
+//	log.SetLogger(log.Tee(
+//		log.Sink{Writer: os.Stdout, Encoder: &log.TextEncoder{Flags: stdlog.LstdFlags, Color: true}},
+//		log.Sink{Writer: rotateWriter, Encoder: &log.JSONEncoder{}, Level: log.LevelInfo},
+//	))
+func Tee(sinks ...Sink) Logger {
+	l := newDefaultLogger(log.New(io.Discard, "", 0))
+	l.sinks = make([]Sink, len(sinks))
+	for i, sink := range sinks {
+		if sink.Encoder == nil {
+			sink.Encoder = NewTextEncoder()
+		}
+		l.sinks[i] = sink
+	}
+	return l
+}
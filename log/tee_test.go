@@ -0,0 +1,113 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTee(t *testing.T) {
+	var text, json bytes.Buffer
+	logger := Tee(
+		Sink{Writer: &text, Encoder: &TextEncoder{Color: true}},
+		Sink{Writer: &json, Encoder: &JSONEncoder{}, Level: LevelWarn},
+	)
+	logger.Info("started")
+	logger.Warnf("slow %d", 3)
+	if out := text.String(); !strings.Contains(out, "\u001b[32mINFO \u001b[0m: started") || !strings.Contains(out, "slow 3") {
+		t.Fatal("unexpected text sink output:", out)
+	}
+	if out := json.String(); strings.Contains(out, "started") || !strings.Contains(out, `"msg":"slow 3"`) {
+		t.Fatal("unexpected json sink output:", out)
+	}
+
+	defer SetLevel(GetLevel())
+	SetLevel(LevelError)
+	logger.Warn("ignored")
+	if strings.Contains(text.String(), "ignored") || strings.Contains(json.String(), "ignored") {
+		t.Fatal("the package level is not applied to the sinks")
+	}
+}
+
+func TestSyslogEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	e := &Entry{Time: time.Date(2018, 9, 18, 19, 0, 0, 0, time.UTC), Level: LevelError, Message: "disk full", Fields: []interface{}{"path", "/data"}}
+	(&SyslogEncoder{Facility: FacilityLocal0, AppName: "app", Hostname: "host"}).Encode(&buf, e)
+	expect := "<131>1 2018-09-18T19:00:00.000000Z host app "
+	if out := buf.String(); !strings.HasPrefix(out, expect) || !strings.HasSuffix(out, " - - disk full path=/data") {
+		t.Fatal("unexpected syslog message:", out)
+	}
+	buf.Reset()
+	e.Stack = "main.main\n\tmain.go:10\n"
+	(&SyslogEncoder{}).Encode(&buf, e)
+	if out := buf.String(); !strings.HasSuffix(out, "disk full path=/data\nmain.main\n\tmain.go:10") {
+		t.Fatalf("stack trace is missing: %q", out)
+	}
+}
+
+func TestDialSyslogStream(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		b, _ := io.ReadAll(conn)
+		received <- string(b)
+	}()
+	w, err := DialSyslog("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("<14>1 a\n"))
+	w.Write([]byte("<14>1 bc\n"))
+	w.(io.Closer).Close()
+	if out := <-received; out != "7 <14>1 a8 <14>1 bc" {
+		t.Fatalf("unexpected frames %q", out)
+	}
+}
+
+func TestDialSyslogReconnect(t *testing.T) {
+	dir, err := os.MkdirTemp("", "syslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log")
+	listen := func() *net.UnixConn {
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+	server := listen()
+	w, err := DialSyslog("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.(io.Closer).Close()
+	server.Close()
+	os.Remove(path)
+	server = listen()
+	defer server.Close()
+	if _, err := w.Write([]byte("<14>1 after restart")); err != nil {
+		t.Fatal("no reconnect:", err)
+	}
+	b := make([]byte, 64)
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	if n, _ := server.Read(b); string(b[:n]) != "<14>1 after restart" {
+		t.Fatalf("unexpected message %q", b[:n])
+	}
+}
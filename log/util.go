@@ -31,6 +31,17 @@ func (o *outPut) Write(p []byte) (n int, err error) {
 	return o.realOut.Write(p)
 }
 
+// Flush flushes the real output when it is a Flusher.
+func (o *outPut) Flush() error {
+	o.mu.Lock()
+	w := o.realOut
+	o.mu.Unlock()
+	if f, ok := w.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
 func (o *outPut) SetWrite(w io.Writer) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
@@ -51,16 +62,26 @@ func SetDefaultFileOutPut(filePath string) (err error) {
 	return nil
 }
 
+// SetDefaultRotateOutput makes the default logger write to a RotateWriter at filePath.
+func SetDefaultRotateOutput(filePath string, cfg RotateConfig) (*RotateWriter, error) {
+	w, err := NewRotateWriter(filePath, cfg)
+	if err != nil {
+		return nil, err
+	}
+	out.SetWrite(w)
+	return w, nil
+}
+
 func SetDefaultOutput(o io.Writer) (err error) {
 	out.SetWrite(o)
 	return nil
 }
 
 
-func NewDefaultLogger(out io.Writer) *defaultLogger {
-	return &defaultLogger{
-		log.New(out, "", log.LstdFlags|log.Lshortfile),
-	}
+// NewDefaultLogger returns a logger writing to out,
+// it follows the package level threshold unless UseLevel is given.
+func NewDefaultLogger(out io.Writer, opts ...Option) *defaultLogger {
+	return newDefaultLogger(log.New(out, "", log.LstdFlags|log.Lshortfile), opts...)
 }
 
 func NewFileLogOutput(logPath string) (file *os.File, err error) {
@@ -73,6 +94,11 @@ func NewFileLogOutput(logPath string) (file *os.File, err error) {
 	return os.OpenFile(logPath, os.O_RDWR | os.O_CREATE | os.O_APPEND, 0700)
 }
 
+// Flush writes out the lines buffered by the default output, such as an AsyncWriter.
+func Flush() error {
+	return out.Flush()
+}
+
 func GetDefaultLoggerOutput() io.Writer {
 	return out
 }
\ No newline at end of file
@@ -0,0 +1,436 @@
+package sqlsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// Node is a node of a parsed q query, a *Group or a *Cond.
+type Node interface {
+	json.Marshaler
+	node()
+}
+
+// Group joins its nodes with Op, one of "$and", "$or" and "$nor".
+// The root of a query is an "$and" group of the members of the q object.
+type Group struct {
+	Op    string
+	Nodes []Node
+}
+
+// Cond is an operator applied to a field, {"age":{"$gt":18}} is Cond{Field: "age", Op: "$gt", Value: 18.0}.
+// Op is empty for plain values such as {"name":"bob"} or {"desc":"/text/"}. Value holds the JSON value,
+// with the conditions of $not and $elemMatch objects as []*Cond.
+type Cond struct {
+	Field string
+	Op    string
+	Value interface{}
+	// Options are the $options of a $regex.
+	Options string
+}
+
+func (*Group) node() {}
+func (*Cond) node()  {}
+
+// ParseQ parses the JSON of the q parameter. Members of $and, $or and $nor are either
+// an object or an array of objects, which repeats fields:
+//
+//	g, err := sqlsearch.ParseQ(`{"age":{"$gte":18},"$or":[{"name":"/bob/"},{"name":"/alice/"}]}`)
+//
+// The error is a *ValidationError.
+func ParseQ(q string) (*Group, error) {
+	g, err := parseQ(q)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func parseQ(q string) (*Group, *ValidationError) {
+	p := &qParser{dec: json.NewDecoder(strings.NewReader(q))}
+	g := &Group{Op: "$and"}
+	if err := p.delim('{', "", ""); err != nil {
+		return nil, err
+	}
+	if err := p.object(g); err != nil {
+		return nil, err
+	}
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, p.invalid("", "", "invalid JSON: trailing data")
+	}
+	return g, nil
+}
+
+// UnmarshalJSON parses a q query into g.
+func (g *Group) UnmarshalJSON(b []byte) error {
+	parsed, err := ParseQ(string(b))
+	if err != nil {
+		return err
+	}
+	*g = *parsed
+	return nil
+}
+
+// MarshalJSON writes g as a q query, an "$and" group is written as a single object when
+// its members don't repeat, otherwise groups are written in the array form.
+func (g *Group) MarshalJSON() ([]byte, error) {
+	if g.Op == "$and" {
+		if b, ok, err := g.object(); ok || err != nil {
+			return b, err
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	writeKey(&buf, g.Op)
+	if err := g.array(&buf); err != nil {
+		return nil, err
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// array writes the nodes of g as a JSON array.
+func (g *Group) array(buf *bytes.Buffer) error {
+	buf.WriteString("[")
+	for i, n := range g.Nodes {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		b, err := n.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	buf.WriteString("]")
+	return nil
+}
+
+// object writes the nodes of g as the members of one object, ok is false if a member repeats.
+func (g *Group) object() (b []byte, ok bool, err error) {
+	var keys []string
+	groups := map[string]*Group{}
+	fields := map[string][]*Cond{}
+	for _, n := range g.Nodes {
+		switch t := n.(type) {
+		case *Group:
+			if _, dup := groups[t.Op]; dup {
+				return nil, false, nil
+			}
+			groups[t.Op] = t
+			keys = append(keys, t.Op)
+		case *Cond:
+			conds, dup := fields[t.Field]
+			if dup && (t.Op == "" || conds[0].Op == "") {
+				return nil, false, nil
+			}
+			for _, c := range conds {
+				if c.Op == t.Op || (c.Op == "$regex" && t.Op == "$options") || (c.Op == "$options" && t.Op == "$regex") {
+					return nil, false, nil
+				}
+			}
+			if !dup {
+				keys = append(keys, t.Field)
+			}
+			fields[t.Field] = append(conds, t)
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		writeKey(&buf, k)
+		if grp, ok := groups[k]; ok {
+			err = grp.array(&buf)
+		} else if conds := fields[k]; conds[0].Op == "" {
+			err = writeValue(&buf, conds[0].Value)
+		} else {
+			err = writeOps(&buf, conds)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), true, nil
+}
+
+// MarshalJSON writes c as a q query of one field.
+func (c *Cond) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	writeKey(&buf, c.Field)
+	var err error
+	if c.Op == "" {
+		err = writeValue(&buf, c.Value)
+	} else {
+		err = writeOps(&buf, []*Cond{c})
+	}
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+func writeKey(buf *bytes.Buffer, key string) {
+	b, _ := json.Marshal(key)
+	buf.Write(b)
+	buf.WriteString(":")
+}
+
+// writeOps writes the operators of conds as an object.
+func writeOps(buf *bytes.Buffer, conds []*Cond) error {
+	buf.WriteString("{")
+	for i, c := range conds {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		writeKey(buf, c.Op)
+		if err := writeValue(buf, c.Value); err != nil {
+			return err
+		}
+		if c.Options != "" {
+			buf.WriteString(",")
+			writeKey(buf, "$options")
+			writeValue(buf, c.Options)
+		}
+	}
+	buf.WriteString("}")
+	return nil
+}
+
+func writeValue(buf *bytes.Buffer, v interface{}) error {
+	if conds, ok := v.([]*Cond); ok {
+		return writeOps(buf, conds)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+// qParser reads a q query token by token, keeping the order of the members.
+type qParser struct {
+	dec *json.Decoder
+}
+
+func (p *qParser) invalid(field, op, message string) *ValidationError {
+	return &ValidationError{Param: "q", Field: field, Operator: op, Message: message}
+}
+
+func (p *qParser) token() (json.Token, *ValidationError) {
+	t, err := p.dec.Token()
+	if err == io.EOF {
+		return nil, p.invalid("", "", "invalid JSON: unexpected end of JSON input")
+	} else if err != nil {
+		return nil, p.invalid("", "", "invalid JSON: "+err.Error())
+	}
+	return t, nil
+}
+
+func (p *qParser) delim(d json.Delim, field, op string) *ValidationError {
+	t, err := p.token()
+	if err != nil {
+		return err
+	}
+	if t != d {
+		return p.invalid(field, op, "expects an object")
+	}
+	return nil
+}
+
+// object parses the members of an object into g, the opening brace is already read.
+// The operators of a field are a group of their own in the groups other than "$and".
+func (p *qParser) object(g *Group) *ValidationError {
+	for p.dec.More() {
+		key, err := p.key()
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(key, "$") {
+			grp, err := p.group(key)
+			if err != nil {
+				return err
+			}
+			g.Nodes = append(g.Nodes, grp)
+			continue
+		}
+		conds, err := p.field(key)
+		if err != nil {
+			return err
+		}
+		if len(conds) > 1 && g.Op != "$and" {
+			g.Nodes = append(g.Nodes, &Group{Op: "$and", Nodes: conds})
+			continue
+		}
+		g.Nodes = append(g.Nodes, conds...)
+	}
+	_, err := p.token()
+	return err
+}
+
+func (p *qParser) key() (string, *ValidationError) {
+	t, err := p.token()
+	if err != nil {
+		return "", err
+	}
+	key, _ := t.(string)
+	return key, nil
+}
+
+// group parses the object or the array of objects of a group operator.
+func (p *qParser) group(op string) (*Group, *ValidationError) {
+	t, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+	g := &Group{Op: op}
+	switch t {
+	case json.Delim('{'):
+		return g, p.object(g)
+	case json.Delim('['):
+		for p.dec.More() {
+			if err := p.delim('{', "", op); err != nil {
+				return nil, p.invalid("", op, "expects an array of objects")
+			}
+			elem := &Group{Op: "$and"}
+			if err := p.object(elem); err != nil {
+				return nil, err
+			}
+			if len(elem.Nodes) == 1 {
+				g.Nodes = append(g.Nodes, elem.Nodes[0])
+			} else {
+				g.Nodes = append(g.Nodes, elem)
+			}
+		}
+		_, err := p.token()
+		return g, err
+	}
+	return nil, p.invalid("", op, "expects an object or an array")
+}
+
+// field parses the value of a field, a plain value or an object of operators.
+func (p *qParser) field(field string) ([]Node, *ValidationError) {
+	t, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+	if t != json.Delim('{') {
+		v, err := p.value(t)
+		if err != nil {
+			return nil, err
+		}
+		return []Node{&Cond{Field: field, Value: v}}, nil
+	}
+	conds, err := p.ops(field)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]Node, len(conds))
+	for i, c := range conds {
+		nodes[i] = c
+	}
+	return nodes, nil
+}
+
+// ops parses an object of operators, the opening brace is already read.
+// $options is set on the $regex next to it.
+func (p *qParser) ops(field string) ([]*Cond, *ValidationError) {
+	conds := []*Cond{}
+	var regex, options *Cond
+	for p.dec.More() {
+		op, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		t, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		c := &Cond{Field: field, Op: op}
+		if (op == "$not" || op == "$elemMatch") && t == json.Delim('{') {
+			c.Value, err = p.ops(field)
+		} else {
+			c.Value, err = p.value(t)
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "$regex":
+			regex = c
+		case "$options":
+			options = c
+		}
+		conds = append(conds, c)
+	}
+	if _, err := p.token(); err != nil {
+		return nil, err
+	}
+	if regex == nil || options == nil {
+		return conds, nil
+	}
+	s, ok := options.Value.(string)
+	if !ok {
+		return nil, p.invalid(field, "$options", "expects a string")
+	}
+	regex.Options = s
+	for i, c := range conds {
+		if c == options {
+			conds = append(conds[:i], conds[i+1:]...)
+			break
+		}
+	}
+	return conds, nil
+}
+
+// value returns the JSON value starting with t, objects are read as an OrderedMap.
+func (p *qParser) value(t json.Token) (interface{}, *ValidationError) {
+	switch t {
+	case json.Delim('{'):
+		m := OrderedMap{values: map[string]interface{}{}}
+		for p.dec.More() {
+			k, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			vt, err := p.token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := p.value(vt)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := m.values[k]; !ok {
+				m.keys = append(m.keys, k)
+			}
+			m.values[k] = v
+		}
+		_, err := p.token()
+		return m, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for p.dec.More() {
+			vt, err := p.token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := p.value(vt)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err := p.token()
+		return list, err
+	}
+	return t, nil
+}
@@ -0,0 +1,63 @@
+package sqlsearch
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseQ(t *testing.T) {
+	for _, c := range []struct {
+		q     string
+		where string
+		json  string
+	}{
+		{`{"name":"bob","age":{"$gt":1,"$lt":5}}`, "name = 'bob' AND age > 1 AND age < 5", ""},
+		{`{"$or":[{"a":1},{"a":2}]}`, "a = 1 OR a = 2", ""},
+		{`{"$or":{"a":1,"b":2},"c":3}`, "(a = 1 OR b = 2) AND c = 3", `{"$or":[{"a":1},{"b":2}],"c":3}`},
+		{`{"$or":[{"a":1,"b":2},{"$and":[{"c":3},{"$or":[{"d":4},{"e":5}]}]}]}`, "(a = 1 AND b = 2) OR (c = 3 AND (d = 4 OR e = 5))",
+			`{"$or":[{"a":1,"b":2},{"c":3,"$or":[{"d":4},{"e":5}]}]}`},
+		{`{"$or":[{"b":{"$gt":1,"$lt":5}},{"b":null}]}`, "(b > 1 AND b < 5) OR b IS NULL", ""},
+		{`{"$nor":[{"a":1},{"b":"/x/"}],"c":{"$not":{"$in":[1,2]}}}`, "NOT (a = 1 OR b LIKE '%x%') AND NOT (c IN (1,2))", ""},
+		{`{"name":{"$regex":"^b","$options":"i"}}`, "REGEXP_LIKE(name, '^b', 'i')", ""},
+		{`{"a":1,"$or":[]}`, "a = 1", ""},
+	} {
+		g, err := ParseQ(c.q)
+		if err != nil {
+			t.Fatal(c.q, err)
+		}
+		if where := Q2Sql(c.q, nil, nil); where != c.where {
+			t.Fatalf("%s: unexpected where %s", c.q, where)
+		}
+		b, err := json.Marshal(g)
+		if c.json == "" {
+			c.json = c.q
+		}
+		if err != nil || string(b) != c.json {
+			t.Fatalf("%s: unexpected JSON %s %v", c.q, b, err)
+		}
+		var g2 Group
+		if err := json.Unmarshal(b, &g2); err != nil || Q2Sql(string(b), nil, nil) != c.where {
+			t.Fatalf("%s: round trip failed %v", c.q, err)
+		}
+	}
+
+	g := &Group{Op: "$and", Nodes: []Node{&Cond{Field: "a", Op: "$gt", Value: 1}, &Cond{Field: "a", Op: "$gt", Value: 2}}}
+	if b, _ := json.Marshal(g); string(b) != `{"$and":[{"a":{"$gt":1}},{"a":{"$gt":2}}]}` {
+		t.Fatal("repeated operators should use the array form", string(b))
+	}
+
+	for _, c := range []struct {
+		q   string
+		err string
+	}{
+		{`{"a":1`, `sqlsearch: q: invalid JSON: unexpected end of JSON input`},
+		{`{"$or":1}`, `sqlsearch: q: $or: expects an object or an array`},
+		{`{"$or":[1]}`, `sqlsearch: q: $or: expects an array of objects`},
+		{`{"a":{"$regex":"x","$options":1}}`, `sqlsearch: q: field "a": $options: expects a string`},
+		{`{"a":1} {}`, `sqlsearch: q: invalid JSON: trailing data`},
+	} {
+		if _, err := ParseQ(c.q); err == nil || err.Error() != c.err {
+			t.Fatalf("%s: unexpected error %v", c.q, err)
+		}
+	}
+}
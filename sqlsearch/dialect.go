@@ -0,0 +1,258 @@
+package sqlsearch
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect controls the SQL syntax written for a database.
+type Dialect interface {
+	// QuoteIdent quotes a column name, such as `name` or "name".
+	QuoteIdent(name string) string
+	// QuoteString returns s as a string literal.
+	QuoteString(s string) string
+	// Placeholder formats the n-th bind parameter, n starts at 1.
+	Placeholder(n int) string
+	// Bool returns a boolean literal.
+	Bool(b bool) string
+	// Time returns a timestamp literal.
+	Time(t time.Time) string
+	// ILike returns a case insensitive LIKE condition of col and pattern.
+	ILike(col, pattern string) string
+	// LimitOffset returns the clause following ORDER BY that pages the rows,
+	// hasOrder reports whether the query has an ORDER BY clause.
+	LimitOffset(limit, offset int, hasOrder bool) string
+}
+
+// RegexpDialect is implemented by the dialects supporting $regex, pattern is a literal or a placeholder.
+type RegexpDialect interface {
+	Regexp(col, pattern string, ignoreCase bool) string
+}
+
+// ArrayDialect is implemented by the dialects with array columns, for $all, $size and $elemMatch.
+type ArrayDialect interface {
+	// ArrayContains returns the condition of col holding all values.
+	ArrayContains(col string, values []string) string
+	// ArrayLength returns the number of elements of col.
+	ArrayLength(col string) string
+	// ArrayElements returns a FROM item listing the elements of col and the column of an element.
+	ArrayElements(col string) (from, elem string)
+}
+
+// JSONDialect is implemented by the dialects reading the properties of JSON columns.
+type JSONDialect interface {
+	// JSONPath returns the property at path of col as text, or as a number when numeric is set.
+	JSONPath(col string, path []string, numeric bool) string
+}
+
+// Dialects of the common databases.
+var (
+	MySQL     Dialect = mysqlDialect{}
+	Postgres  Dialect = postgresDialect{}
+	SQLite    Dialect = sqliteDialect{}
+	SQLServer Dialect = sqlServerDialect{}
+)
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) QuoteIdent(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// QuoteString escapes backslashes too, which start escape sequences in MySQL string literals.
+func (mysqlDialect) QuoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(s) + "'"
+}
+
+func (mysqlDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (mysqlDialect) Bool(b bool) string {
+	return strings.ToUpper(strconv.FormatBool(b))
+}
+
+func (mysqlDialect) Time(t time.Time) string {
+	return "'" + t.Format("2006-01-02 15:04:05") + "'"
+}
+
+func (mysqlDialect) ILike(col, pattern string) string {
+	return "LOWER(" + col + ") LIKE LOWER(" + pattern + ")"
+}
+
+func (mysqlDialect) LimitOffset(limit, offset int, hasOrder bool) string {
+	return limitOffset(limit, offset)
+}
+
+func (mysqlDialect) Regexp(col, pattern string, ignoreCase bool) string {
+	if ignoreCase {
+		return "REGEXP_LIKE(" + col + ", " + pattern + ", 'i')"
+	}
+	return "REGEXP_LIKE(" + col + ", " + pattern + ", 'c')"
+}
+
+func (mysqlDialect) JSONPath(col string, path []string, numeric bool) string {
+	s := "JSON_EXTRACT(" + col + ", '$." + strings.Join(path, ".") + "')"
+	if numeric {
+		return s
+	}
+	return "JSON_UNQUOTE(" + s + ")"
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func (postgresDialect) QuoteString(s string) string {
+	return quote(s)
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDialect) Bool(b bool) string {
+	return strings.ToUpper(strconv.FormatBool(b))
+}
+
+func (postgresDialect) Time(t time.Time) string {
+	return "'" + t.Format("2006-01-02 15:04:05.999999-07:00") + "'"
+}
+
+func (postgresDialect) ILike(col, pattern string) string {
+	return col + " ILIKE " + pattern
+}
+
+func (postgresDialect) LimitOffset(limit, offset int, hasOrder bool) string {
+	return limitOffset(limit, offset)
+}
+
+func (postgresDialect) Regexp(col, pattern string, ignoreCase bool) string {
+	if ignoreCase {
+		return col + " ~* " + pattern
+	}
+	return col + " ~ " + pattern
+}
+
+func (postgresDialect) ArrayContains(col string, values []string) string {
+	return col + " @> ARRAY[" + strings.Join(values, ",") + "]"
+}
+
+func (postgresDialect) ArrayLength(col string) string {
+	return "cardinality(" + col + ")"
+}
+
+func (postgresDialect) ArrayElements(col string) (from, elem string) {
+	return "unnest(" + col + ") AS _e", "_e"
+}
+
+func (postgresDialect) JSONPath(col string, path []string, numeric bool) string {
+	s := col + " #>> '{" + strings.Join(path, ",") + "}'"
+	if numeric {
+		return "(" + s + ")::numeric"
+	}
+	return s
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) QuoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func (sqliteDialect) QuoteString(s string) string {
+	return quote(s)
+}
+
+func (sqliteDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (sqliteDialect) Bool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func (sqliteDialect) Time(t time.Time) string {
+	return "'" + t.Format("2006-01-02 15:04:05") + "'"
+}
+
+// ILike uses LIKE, which ignores the case of ASCII letters in SQLite.
+func (sqliteDialect) ILike(col, pattern string) string {
+	return col + " LIKE " + pattern
+}
+
+func (sqliteDialect) LimitOffset(limit, offset int, hasOrder bool) string {
+	return limitOffset(limit, offset)
+}
+
+// Regexp needs a REGEXP function registered with the connection, most drivers provide one.
+func (sqliteDialect) Regexp(col, pattern string, ignoreCase bool) string {
+	if ignoreCase {
+		return col + " REGEXP '(?i)' || " + pattern
+	}
+	return col + " REGEXP " + pattern
+}
+
+func (sqliteDialect) JSONPath(col string, path []string, numeric bool) string {
+	return "json_extract(" + col + ", '$." + strings.Join(path, ".") + "')"
+}
+
+type sqlServerDialect struct{}
+
+func (sqlServerDialect) QuoteIdent(name string) string {
+	return "[" + strings.Replace(name, "]", "]]", -1) + "]"
+}
+
+func (sqlServerDialect) QuoteString(s string) string {
+	return "N" + quote(s)
+}
+
+func (sqlServerDialect) Placeholder(n int) string {
+	return "@p" + strconv.Itoa(n)
+}
+
+func (sqlServerDialect) Bool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func (sqlServerDialect) Time(t time.Time) string {
+	return "'" + t.Format("2006-01-02T15:04:05.000") + "'"
+}
+
+func (sqlServerDialect) ILike(col, pattern string) string {
+	return "LOWER(" + col + ") LIKE LOWER(" + pattern + ")"
+}
+
+// LimitOffset uses OFFSET FETCH, which needs an ORDER BY clause, so one is added when missing.
+func (sqlServerDialect) LimitOffset(limit, offset int, hasOrder bool) string {
+	s := "OFFSET " + strconv.Itoa(offset) + " ROWS FETCH NEXT " + strconv.Itoa(limit) + " ROWS ONLY"
+	if !hasOrder {
+		s = "ORDER BY (SELECT NULL) " + s
+	}
+	return s
+}
+
+func (sqlServerDialect) JSONPath(col string, path []string, numeric bool) string {
+	s := "JSON_VALUE(" + col + ", '$." + strings.Join(path, ".") + "')"
+	if numeric {
+		return "CAST(" + s + " AS FLOAT)"
+	}
+	return s
+}
+
+func limitOffset(limit, offset int) string {
+	s := "LIMIT " + strconv.Itoa(limit)
+	if offset > 0 {
+		s += " OFFSET " + strconv.Itoa(offset)
+	}
+	return s
+}
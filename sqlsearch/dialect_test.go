@@ -0,0 +1,62 @@
+package sqlsearch
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDialect(t *testing.T) {
+	uriQuery := url.Values{
+		"q":     {`{"name":"o'k\\","desc":"/50%/","active":true,"created_at":{"$gte":"2010-05-18T00:00:00+08:00"}}`},
+		"sort":  {`["-created_at"]`},
+		"limit": {"10"},
+		"page":  {"3"},
+	}
+	local, _ := time.LoadLocation("Asia/Shanghai")
+	for _, c := range []struct {
+		dialect Dialect
+		where   string
+		args    string
+		order   string
+		paging  string
+	}{
+		{MySQL, "`name` = 'o''k\\\\' AND `desc` LIKE '%50!%%' ESCAPE '!' AND `active` = TRUE AND `created_at` >= '2010-05-18 00:00:00'",
+			"`name` = ? AND `desc` LIKE ? ESCAPE '!' AND `active` = ? AND `created_at` >= ?", "`created_at` DESC", "LIMIT 10 OFFSET 20"},
+		{Postgres, `"name" = 'o''k\' AND "desc" LIKE '%50!%%' ESCAPE '!' AND "active" = TRUE AND "created_at" >= '2010-05-18 00:00:00+08:00'`,
+			`"name" = $1 AND "desc" LIKE $2 ESCAPE '!' AND "active" = $3 AND "created_at" >= $4`, `"created_at" DESC`, "LIMIT 10 OFFSET 20"},
+		{SQLite, `"name" = 'o''k\' AND "desc" LIKE '%50!%%' ESCAPE '!' AND "active" = 1 AND "created_at" >= '2010-05-18 00:00:00'`,
+			`"name" = ? AND "desc" LIKE ? ESCAPE '!' AND "active" = ? AND "created_at" >= ?`, `"created_at" DESC`, "LIMIT 10 OFFSET 20"},
+		{SQLServer, `[name] = N'o''k\' AND [desc] LIKE N'%50!%%' ESCAPE '!' AND [active] = 1 AND [created_at] >= '2010-05-18T00:00:00.000'`,
+			`[name] = @p1 AND [desc] LIKE @p2 ESCAPE '!' AND [active] = @p3 AND [created_at] >= @p4`, `[created_at] DESC`, "OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+	} {
+		q := New(uriQuery, local, nil, c.dialect)
+		sq := q.ToSql()
+		if sq.Where != c.where || sq.Order != c.order || sq.Paging != c.paging {
+			t.Fatalf("%T: unexpected query %+v", c.dialect, sq)
+		}
+		sq, args, err := q.ToSqlArgs()
+		if err != nil || sq.Where != c.args || len(args) != 4 {
+			t.Fatalf("%T: unexpected query %+v %v %v", c.dialect, sq, args, err)
+		}
+	}
+
+	for dialect, where := range map[Dialect]string{
+		SQLServer: `[name] LIKE N'%a![b-c]%' ESCAPE '!' AND [code] LIKE N'![x%' ESCAPE '!'`,
+		Postgres:  `"name" LIKE '%a[b-c]%' ESCAPE '!' AND "code" LIKE '[x%' ESCAPE '!'`,
+	} {
+		if sq := New(url.Values{"q": {`{"name":"/a[b-c]/","code":{"$startsWith":"[x"}}`}}, nil, nil, dialect).ToSql(); sq.Where != where {
+			t.Fatalf("%T: unexpected where %s", dialect, sq.Where)
+		}
+	}
+
+	if p := SQLServer.LimitOffset(5, 0, false); p != "ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 5 ROWS ONLY" {
+		t.Fatal("unexpected paging", p)
+	}
+	if s := Postgres.ILike(`"name"`, "$1"); s != `"name" ILIKE $1` {
+		t.Fatal("unexpected ILIKE", s)
+	}
+	if sq := New(uriQuery, local, func(k string) string { return "LOWER(" + k + ")" }, Postgres).ToSql(); sq.Order != "LOWER(created_at) DESC" {
+		t.Fatal("expressions should not be quoted", sq.Order)
+	}
+}
@@ -0,0 +1,265 @@
+package sqlsearch
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ToElastic renders q as the JSON body of an Elasticsearch search request. Conditions are filters
+// on the exact values of the fields, so text fields should be queried by their keyword sub fields:
+// "/text/" and the LIKE operators become wildcard or prefix queries and $regex a regexp query,
+// which take Lucene regular expressions. $size and $elemMatch need the mapping of the index and
+// are not supported, a single distinct field of select collapses the hits.
+//
+//	body, err := sqlsearch.ToElastic(query)
+//	res, err := es.Search(es.Search.WithIndex("users"), es.Search.WithBody(bytes.NewReader(body)))
+func ToElastic(q *Query) ([]byte, error) {
+	e := &elasticWriter{}
+	body := map[string]interface{}{
+		"query": map[string]interface{}{"match_all": map[string]interface{}{}},
+		"from":  q.Offset,
+		"size":  q.Limit,
+	}
+	if q.Filter != nil {
+		body["query"] = e.group(q.Filter)
+	}
+	if len(q.Sort) > 0 {
+		sorts := make([]interface{}, len(q.Sort))
+		for i, s := range q.Sort {
+			order := "asc"
+			if s.Desc {
+				order = "desc"
+			}
+			sorts[i] = map[string]interface{}{s.Field: map[string]interface{}{"order": order}}
+		}
+		body["sort"] = sorts
+	}
+	var source []string
+	for _, p := range q.Select {
+		if p.Distinct {
+			if _, ok := body["collapse"]; ok {
+				e.errs = append(e.errs, &ValidationError{Param: "select", Field: p.Field, Message: "only one distinct field is supported"})
+			}
+			body["collapse"] = map[string]interface{}{"field": p.Field}
+		}
+		source = append(source, p.Field)
+	}
+	if source != nil {
+		body["_source"] = source
+	}
+	if len(e.errs) > 0 {
+		return nil, e.errs
+	}
+	return json.Marshal(body)
+}
+
+type elasticWriter struct {
+	errs ValidationErrors
+}
+
+func (e *elasticWriter) invalid(field, op, msg string) {
+	e.errs = append(e.errs, &ValidationError{Param: "q", Field: field, Operator: op, Message: msg})
+}
+
+func boolQuery(occur string, clauses []interface{}) map[string]interface{} {
+	return map[string]interface{}{"bool": map[string]interface{}{occur: clauses}}
+}
+
+func notQuery(query interface{}) map[string]interface{} {
+	return boolQuery("must_not", []interface{}{query})
+}
+
+// group returns the bool query of g.
+func (e *elasticWriter) group(g *Group) interface{} {
+	clauses := make([]interface{}, 0, len(g.Nodes))
+	for _, n := range g.Nodes {
+		var clause interface{}
+		switch t := n.(type) {
+		case *Group:
+			clause = e.group(t)
+		case *Cond:
+			clause = e.cond(t)
+		}
+		if clause != nil {
+			clauses = append(clauses, clause)
+		}
+	}
+	switch g.Op {
+	case "$and":
+		return boolQuery("filter", clauses)
+	case "$or":
+		q := boolQuery("should", clauses)
+		q["bool"].(map[string]interface{})["minimum_should_match"] = 1
+		return q
+	case "$nor":
+		return boolQuery("must_not", clauses)
+	}
+	e.invalid("", g.Op, "unknown operator")
+	return nil
+}
+
+// cond returns the query of a field condition, nil if it is invalid.
+func (e *elasticWriter) cond(c *Cond) interface{} {
+	f := c.Field
+	if c.Op == "" {
+		switch v := c.Value.(type) {
+		case string:
+			if len(v) >= 2 && strings.HasPrefix(v, "/") && strings.HasSuffix(v, "/") {
+				return wildcardQuery(f, "*"+escapeWildcard(v[1:len(v)-1])+"*", false)
+			}
+		case nil:
+			return notQuery(existsQuery(f))
+		}
+		return termQuery(f, c.Value)
+	}
+	grp, ok := mg2SqlGroup[c.Op]
+	if !ok || grp.Type == opGroup {
+		e.invalid(f, c.Op, "unknown operator")
+		return nil
+	}
+	v := c.Value
+	switch c.Op {
+	case "$eq":
+		if v == nil {
+			return notQuery(existsQuery(f))
+		}
+		return termQuery(f, v)
+	case "$ne":
+		if v == nil {
+			return existsQuery(f)
+		}
+		return notQuery(termQuery(f, v))
+	case "$gt", "$gte", "$lt", "$lte":
+		return rangeQuery(f, map[string]interface{}{c.Op[1:]: v})
+	case "$in", "$nin":
+		list, ok := v.([]interface{})
+		if !ok {
+			e.invalid(f, c.Op, "expects an array")
+			return nil
+		}
+		q := map[string]interface{}{"terms": map[string]interface{}{f: list}}
+		if c.Op == "$nin" {
+			return notQuery(q)
+		}
+		return q
+	case "$exists", "$null":
+		b, ok := v.(bool)
+		if !ok {
+			e.invalid(f, c.Op, "expects a boolean")
+			return nil
+		}
+		if b == (c.Op == "$exists") {
+			return existsQuery(f)
+		}
+		return notQuery(existsQuery(f))
+	case "$like", "$ilike", "$startsWith", "$endsWith":
+		s, ok := v.(string)
+		if !ok {
+			e.invalid(f, c.Op, "expects a string")
+			return nil
+		}
+		switch c.Op {
+		case "$startsWith":
+			return map[string]interface{}{"prefix": map[string]interface{}{f: map[string]interface{}{"value": s}}}
+		case "$endsWith":
+			return wildcardQuery(f, "*"+escapeWildcard(s), false)
+		}
+		return wildcardQuery(f, likeWildcard(s), c.Op == "$ilike")
+	case "$regex":
+		s, ok := v.(string)
+		if !ok {
+			e.invalid(f, c.Op, "expects a string")
+			return nil
+		}
+		if c.Options != "" && c.Options != "i" {
+			e.invalid(f, "$options", "only the i option is supported")
+			return nil
+		}
+		q := map[string]interface{}{"value": s}
+		if c.Options == "i" {
+			q["case_insensitive"] = true
+		}
+		return map[string]interface{}{"regexp": map[string]interface{}{f: q}}
+	case "$options":
+		e.invalid(f, c.Op, "needs $regex")
+	case "$between":
+		bounds, ok := v.([]interface{})
+		if !ok || len(bounds) != 2 {
+			e.invalid(f, c.Op, "expects an array of 2 values")
+			return nil
+		}
+		return rangeQuery(f, map[string]interface{}{"gte": bounds[0], "lte": bounds[1]})
+	case "$not":
+		switch t := v.(type) {
+		case []*Cond:
+			clauses := make([]interface{}, 0, len(t))
+			for _, c := range t {
+				if q := e.cond(c); q != nil {
+					clauses = append(clauses, q)
+				}
+			}
+			return notQuery(boolQuery("filter", clauses))
+		case string:
+			if len(t) >= 2 && strings.HasPrefix(t, "/") && strings.HasSuffix(t, "/") {
+				return notQuery(e.cond(&Cond{Field: f, Value: t}))
+			}
+		}
+		e.invalid(f, c.Op, "expects an object or a /pattern/")
+	case "$all":
+		list, ok := v.([]interface{})
+		if !ok || len(list) == 0 {
+			e.invalid(f, c.Op, "expects a non empty array")
+			return nil
+		}
+		clauses := make([]interface{}, len(list))
+		for i, x := range list {
+			clauses[i] = termQuery(f, x)
+		}
+		return boolQuery("filter", clauses)
+	default:
+		e.invalid(f, c.Op, "not supported by Elasticsearch")
+	}
+	return nil
+}
+
+func termQuery(field string, v interface{}) map[string]interface{} {
+	return map[string]interface{}{"term": map[string]interface{}{field: v}}
+}
+
+func rangeQuery(field string, bounds map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"range": map[string]interface{}{field: bounds}}
+}
+
+func existsQuery(field string) map[string]interface{} {
+	return map[string]interface{}{"exists": map[string]interface{}{"field": field}}
+}
+
+func wildcardQuery(field, pattern string, ignoreCase bool) map[string]interface{} {
+	q := map[string]interface{}{"value": pattern}
+	if ignoreCase {
+		q["case_insensitive"] = true
+	}
+	return map[string]interface{}{"wildcard": map[string]interface{}{field: q}}
+}
+
+var wildcardEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`)
+
+func escapeWildcard(s string) string {
+	return wildcardEscaper.Replace(s)
+}
+
+// likeWildcard converts a LIKE pattern to a wildcard pattern.
+func likeWildcard(pattern string) string {
+	var b strings.Builder
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString("*")
+		case '_':
+			b.WriteString("?")
+		default:
+			b.WriteString(escapeWildcard(string(r)))
+		}
+	}
+	return b.String()
+}
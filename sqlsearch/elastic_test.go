@@ -0,0 +1,40 @@
+package sqlsearch
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestToElastic(t *testing.T) {
+	for _, c := range []struct {
+		params url.Values
+		body   string
+	}{
+		{url.Values{}, `{"from":0,"query":{"match_all":{}},"size":2000}`},
+		{url.Values{"q": {`{"name":"bob","age":{"$gte":18,"$ne":30},"x":null}`}, "limit": {"10"}, "page": {"2"}},
+			`{"from":10,"query":{"bool":{"filter":[{"term":{"name":"bob"}},{"range":{"age":{"gte":18}}},{"bool":{"must_not":[{"term":{"age":30}}]}},{"bool":{"must_not":[{"exists":{"field":"x"}}]}}]}},"size":10}`},
+		{url.Values{"q": {`{"$or":[{"tags":{"$in":["a","b"]}},{"desc":"/a*b/"}],"$nor":[{"code":{"$like":"x_%"}}]}`}},
+			`{"from":0,"query":{"bool":{"filter":[{"bool":{"minimum_should_match":1,"should":[{"terms":{"tags":["a","b"]}},{"wildcard":{"desc":{"value":"*a\\*b*"}}}]}},{"bool":{"must_not":[{"wildcard":{"code":{"value":"x?*"}}}]}}]}},"size":2000}`},
+		{url.Values{"q": {`{"name":{"$regex":"b.*","$options":"i"},"age":{"$between":[1,2]},"n":{"$startsWith":"a"}}`}},
+			`{"from":0,"query":{"bool":{"filter":[{"regexp":{"name":{"case_insensitive":true,"value":"b.*"}}},{"range":{"age":{"gte":1,"lte":2}}},{"prefix":{"n":{"value":"a"}}}]}},"size":2000}`},
+		{url.Values{"sort": {`["-age"]`}, "select": {`["$name","age"]`}},
+			`{"_source":["name","age"],"collapse":{"field":"name"},"from":0,"query":{"match_all":{}},"size":2000,"sort":[{"age":{"order":"desc"}}]}`},
+	} {
+		q, err := Parse(c.params)
+		if err != nil {
+			t.Fatal(c.params, err)
+		}
+		if b, err := ToElastic(q); err != nil || string(b) != c.body {
+			t.Fatalf("%v: unexpected body %s %v", c.params, b, err)
+		}
+	}
+
+	q, _ := Parse(url.Values{"q": {`{"tags":{"$size":2}}`}})
+	if _, err := ToElastic(q); err == nil || err.Error() != `sqlsearch: q: field "tags": $size: not supported by Elasticsearch` {
+		t.Fatal("unexpected error", err)
+	}
+	q, _ = Parse(url.Values{"q": {`{"name":{"$regex":"a","$options":"m"}}`}})
+	if _, err := ToElastic(q); err == nil || err.Error() != `sqlsearch: q: field "name": $options: only the i option is supported` {
+		t.Fatal("unexpected error", err)
+	}
+}
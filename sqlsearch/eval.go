@@ -0,0 +1,628 @@
+package sqlsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ti/goutil/jsonmap"
+)
+
+// Eval filters, sorts, projects and paginates records with q in memory, following the SQL
+// written by SqlSearch: comparisons with null are unknown and fail like in a WHERE clause,
+// and nulls sort first. LIKE patterns, including "/text/", fold the case like the default
+// collations of the dialect: MySQL and SQLServer ignore the case, SQLite only the case of
+// ASCII letters, and Postgres, other dialects or none are case sensitive. $ilike always
+// ignores the case. LIKE matches the text of a value, so numbers match their decimal form, while the other
+// operators never convert between strings and numbers: "30" does not equal 30.
+// RFC 3339 strings are compared as times. Fields missing from a record are null,
+// dotted fields such as "user.name" or "tags[0]" are paths read with jsonmap.GetProperty.
+// Records are returned as they are without select, otherwise they are new maps of the selected fields.
+// A negative Offset or Limit of a Query built by the caller counts as 0.
+func Eval(q *Query, records []map[string]interface{}, dialect ...Dialect) ([]map[string]interface{}, error) {
+	e := newEvaluator(dialect)
+	return e.apply(q, records)
+}
+
+// Apply parses the list parameters of query and evaluates them on records, see Eval.
+//
+//	users, err := sqlsearch.Apply(r.URL.Query(), cached, sqlsearch.SQLite)
+func Apply(query url.Values, records []map[string]interface{}, dialect ...Dialect) ([]map[string]interface{}, error) {
+	q, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	return Eval(q, records, dialect...)
+}
+
+// Match reports whether record matches the JSON of a q parameter, with the semantics of Eval.
+//
+//	ok, err := sqlsearch.Match(`{"age":{"$gte":18}}`, record)
+func Match(q string, record map[string]interface{}, dialect ...Dialect) (bool, error) {
+	g, perr := parseQ(q)
+	if perr != nil {
+		return false, ValidationErrors{perr}
+	}
+	e := newEvaluator(dialect)
+	ok := e.group(g, record) == isTrue
+	if len(e.errs) > 0 {
+		return false, e.errs
+	}
+	return ok, nil
+}
+
+// property returns the value of field in r, nil if it is missing.
+func property(r map[string]interface{}, field string) interface{} {
+	if v, ok := r[field]; ok || !strings.ContainsAny(field, ".[") {
+		return v
+	}
+	v, err := jsonmap.GetProperty(r, field)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// truth is a value of the three valued logic of SQL.
+type truth int8
+
+const (
+	unknown truth = iota
+	isFalse
+	isTrue
+)
+
+func truthOf(b bool) truth {
+	if b {
+		return isTrue
+	}
+	return isFalse
+}
+
+func (t truth) not() truth {
+	switch t {
+	case isTrue:
+		return isFalse
+	case isFalse:
+		return isTrue
+	}
+	return unknown
+}
+
+type evaluator struct {
+	fold    caseFold
+	regexps map[string]*regexp.Regexp
+	errs    ValidationErrors
+}
+
+// caseFold is how LIKE compares letters.
+type caseFold int
+
+const (
+	foldNone caseFold = iota
+	foldASCII
+	foldAll
+)
+
+func newEvaluator(dialect []Dialect) *evaluator {
+	e := &evaluator{}
+	if len(dialect) > 0 {
+		switch dialect[0].(type) {
+		case mysqlDialect, sqlServerDialect:
+			e.fold = foldAll
+		case sqliteDialect:
+			e.fold = foldASCII
+		}
+	}
+	return e
+}
+
+func (e *evaluator) invalid(field, op, format string, a ...interface{}) {
+	err := &ValidationError{Param: "q", Field: field, Operator: op, Message: fmt.Sprintf(format, a...)}
+	for _, x := range e.errs {
+		if *x == *err {
+			return
+		}
+	}
+	e.errs = append(e.errs, err)
+}
+
+func (e *evaluator) apply(q *Query, records []map[string]interface{}) ([]map[string]interface{}, error) {
+	if q.Filter != nil {
+		// conditions are checked before the records, which may be empty
+		e.group(q.Filter, map[string]interface{}{})
+		if len(e.errs) > 0 {
+			return nil, e.errs
+		}
+	}
+	var rows []map[string]interface{}
+	for _, r := range records {
+		if q.Filter == nil || e.group(q.Filter, r) == isTrue {
+			rows = append(rows, r)
+		}
+	}
+	if len(q.Sort) > 0 {
+		sort.SliceStable(rows, func(i, j int) bool {
+			for _, s := range q.Sort {
+				c := compareNulls(property(rows[i], s.Field), property(rows[j], s.Field))
+				if s.Desc {
+					c = -c
+				}
+				if c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		})
+	}
+	if len(q.Select) > 0 {
+		rows = e.project(q.Select, rows)
+	}
+	if q.Offset >= len(rows) {
+		return []map[string]interface{}{}, nil
+	}
+	if q.Offset > 0 {
+		rows = rows[q.Offset:]
+	}
+	if q.Limit < len(rows) {
+		limit := q.Limit
+		if limit < 0 {
+			limit = 0
+		}
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+// project returns the selected fields of rows, without repeated rows if a field is distinct.
+func (e *evaluator) project(sel []Projection, rows []map[string]interface{}) []map[string]interface{} {
+	var distinct bool
+	for _, p := range sel {
+		distinct = distinct || p.Distinct
+	}
+	seen := map[string]bool{}
+	projected := make([]map[string]interface{}, 0, len(rows))
+	for _, r := range rows {
+		row := make(map[string]interface{}, len(sel))
+		values := make([]interface{}, len(sel))
+		for i, p := range sel {
+			values[i] = property(r, p.Field)
+			row[p.Field] = values[i]
+		}
+		if distinct {
+			key, _ := json.Marshal(values)
+			if seen[string(key)] {
+				continue
+			}
+			seen[string(key)] = true
+		}
+		projected = append(projected, row)
+	}
+	return projected
+}
+
+// group evaluates every node of g, so that the errors of all of them are found.
+func (e *evaluator) group(g *Group, r map[string]interface{}) truth {
+	var res truth
+	switch g.Op {
+	case "$and":
+		res = isTrue
+	case "$or", "$nor":
+		res = isFalse
+	default:
+		e.invalid("", g.Op, "unknown operator")
+		return unknown
+	}
+	for _, n := range g.Nodes {
+		var t truth
+		switch n := n.(type) {
+		case *Group:
+			t = e.group(n, r)
+		case *Cond:
+			t = e.cond(n, property(r, n.Field))
+		}
+		if g.Op == "$and" {
+			res = and(res, t)
+		} else {
+			res = or(res, t)
+		}
+	}
+	if g.Op == "$nor" {
+		return res.not()
+	}
+	return res
+}
+
+func and(a, b truth) truth {
+	if a == isFalse || b == isFalse {
+		return isFalse
+	} else if a == unknown || b == unknown {
+		return unknown
+	}
+	return isTrue
+}
+
+func or(a, b truth) truth {
+	if a == isTrue || b == isTrue {
+		return isTrue
+	} else if a == unknown || b == unknown {
+		return unknown
+	}
+	return isFalse
+}
+
+// cond evaluates a field condition on the value v of the field.
+func (e *evaluator) cond(c *Cond, v interface{}) truth {
+	if c.Op != "" {
+		return e.operator(c, v)
+	}
+	switch t := c.Value.(type) {
+	case string:
+		if len(t) >= 2 && strings.HasPrefix(t, "/") && strings.HasSuffix(t, "/") {
+			return e.like(v, "%"+escapeLike(t[1:len(t)-1])+"%", c)
+		}
+	case nil:
+		return truthOf(v == nil)
+	}
+	return e.operator(&Cond{Field: c.Field, Op: "$eq", Value: c.Value}, v)
+}
+
+// conds evaluates conditions joined by AND.
+func (e *evaluator) conds(conds []*Cond, v interface{}) truth {
+	res := isTrue
+	for _, c := range conds {
+		res = and(res, e.cond(c, v))
+	}
+	return res
+}
+
+func (e *evaluator) operator(c *Cond, v interface{}) truth {
+	op, x := c.Op, c.Value
+	grp, ok := mg2SqlGroup[op]
+	if !ok || grp.Type == opGroup {
+		e.invalid(c.Field, op, "unknown operator")
+		return unknown
+	}
+	switch grp.Type {
+	case opCompare:
+		if x == nil && (op == "$eq" || op == "$ne") {
+			return truthOf((v == nil) == (op == "$eq"))
+		} else if v == nil || x == nil {
+			return unknown
+		}
+		n, ok := compare(v, x)
+		if !ok {
+			return truthOf(op == "$ne")
+		}
+		switch op {
+		case "$eq":
+			return truthOf(n == 0)
+		case "$ne":
+			return truthOf(n != 0)
+		case "$gt":
+			return truthOf(n > 0)
+		case "$gte":
+			return truthOf(n >= 0)
+		case "$lt":
+			return truthOf(n < 0)
+		}
+		return truthOf(n <= 0)
+	case opList:
+		list, ok := x.([]interface{})
+		if !ok {
+			e.invalid(c.Field, op, "expects an array")
+			return unknown
+		}
+		if len(list) == 0 {
+			return truthOf(op == "$nin")
+		} else if v == nil {
+			return unknown
+		}
+		res := isFalse
+		for _, y := range list {
+			if y == nil {
+				res = unknown
+			} else if n, ok := compare(v, y); ok && n == 0 {
+				res = isTrue
+				break
+			}
+		}
+		if op == "$nin" {
+			return res.not()
+		}
+		return res
+	case opExists, opNull:
+		b, ok := x.(bool)
+		if !ok {
+			e.invalid(c.Field, op, "expects a boolean")
+			return unknown
+		}
+		return truthOf((v != nil) == (b == (op == "$exists")))
+	case opLike:
+		s, ok := x.(string)
+		if !ok {
+			e.invalid(c.Field, op, "expects a string")
+			return unknown
+		}
+		switch op {
+		case "$startsWith":
+			s = escapeLike(s) + "%"
+		case "$endsWith":
+			s = "%" + escapeLike(s)
+		}
+		return e.like(v, s, c)
+	case opRegexp:
+		s, ok := x.(string)
+		if !ok {
+			e.invalid(c.Field, op, "expects a string")
+			return unknown
+		}
+		if strings.Trim(c.Options, "i") != "" {
+			e.invalid(c.Field, "$options", "only the i option is supported")
+			return unknown
+		}
+		if c.Options != "" {
+			s = "(?i)" + s
+		}
+		re := e.regexp(c, s)
+		if re == nil || v == nil {
+			return unknown
+		}
+		return truthOf(re.MatchString(text(v)))
+	case opOptions:
+		e.invalid(c.Field, op, "needs $regex")
+	case opBetween:
+		bounds, ok := x.([]interface{})
+		if !ok || len(bounds) != 2 {
+			e.invalid(c.Field, op, "expects an array of 2 values")
+			return unknown
+		}
+		return and(e.operator(&Cond{Field: c.Field, Op: "$gte", Value: bounds[0]}, v),
+			e.operator(&Cond{Field: c.Field, Op: "$lte", Value: bounds[1]}, v))
+	case opNot:
+		switch t := x.(type) {
+		case []*Cond:
+			return e.conds(t, v).not()
+		case string:
+			if len(t) >= 2 && strings.HasPrefix(t, "/") && strings.HasSuffix(t, "/") {
+				return e.cond(&Cond{Field: c.Field, Value: t}, v).not()
+			}
+		}
+		e.invalid(c.Field, op, "expects an object or a /pattern/")
+	case opArray:
+		return e.array(c, v)
+	}
+	return unknown
+}
+
+// array evaluates $all, $size and $elemMatch.
+func (e *evaluator) array(c *Cond, v interface{}) truth {
+	elems, isArray := v.([]interface{})
+	switch c.Op {
+	case "$all":
+		list, ok := c.Value.([]interface{})
+		if !ok || len(list) == 0 {
+			e.invalid(c.Field, c.Op, "expects a non empty array")
+			return unknown
+		}
+		if v == nil {
+			return unknown
+		}
+		for _, y := range list {
+			found := false
+			for _, x := range elems {
+				if n, ok := compare(x, y); ok && n == 0 {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return isFalse
+			}
+		}
+		return truthOf(isArray)
+	case "$size":
+		n, ok := c.Value.(float64)
+		if !ok || n < 0 || n != math.Trunc(n) {
+			e.invalid(c.Field, c.Op, "expects a positive integer")
+			return unknown
+		}
+		if v == nil {
+			return unknown
+		}
+		return truthOf(isArray && len(elems) == int(n))
+	case "$elemMatch":
+		conds, ok := c.Value.([]*Cond)
+		if !ok {
+			e.invalid(c.Field, c.Op, "expects an object")
+			return unknown
+		}
+		if len(elems) == 0 {
+			e.conds(conds, nil)
+			return isFalse
+		}
+		for _, x := range elems {
+			if e.conds(conds, x) == isTrue {
+				return isTrue
+			}
+		}
+	}
+	return isFalse
+}
+
+// like matches v with a LIKE pattern escaped with LikeEscape, folding the case like the dialect.
+func (e *evaluator) like(v interface{}, pattern string, c *Cond) truth {
+	fold := e.fold
+	if c.Op == "$ilike" {
+		fold = foldAll
+	}
+	var b strings.Builder
+	if fold == foldAll {
+		b.WriteString("(?is)^")
+	} else {
+		b.WriteString("(?s)^")
+	}
+	literal := func(r rune) {
+		if fold == foldASCII && ('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z') {
+			b.WriteString("[" + strings.ToLower(string(r)) + strings.ToUpper(string(r)) + "]")
+		} else {
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			literal(r)
+			escaped = false
+		case string(r) == LikeEscape && c.Op != "$like" && c.Op != "$ilike":
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			literal(r)
+		}
+	}
+	b.WriteString("$")
+	re := e.regexp(c, b.String())
+	if re == nil || v == nil {
+		return unknown
+	}
+	return truthOf(re.MatchString(text(v)))
+}
+
+// regexp returns the compiled expr, nil if it is invalid.
+func (e *evaluator) regexp(c *Cond, expr string) *regexp.Regexp {
+	if re, ok := e.regexps[expr]; ok {
+		return re
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		e.invalid(c.Field, c.Op, "%v", err)
+		return nil
+	}
+	if e.regexps == nil {
+		e.regexps = map[string]*regexp.Regexp{}
+	}
+	e.regexps[expr] = re
+	return re
+}
+
+// text returns v as a string like SQL converts values compared with LIKE.
+func text(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	if f, ok := toFloat(v); ok {
+		return fmt.Sprint(f)
+	}
+	return fmt.Sprint(v)
+}
+
+// compareNulls is compare with null lower than other values, ok is ignored.
+func compareNulls(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	n, _ := compare(a, b)
+	return n
+}
+
+// compare compares a and b, ok is false if their types are not comparable.
+func compare(a, b interface{}) (n int, ok bool) {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	if x, ok := toTime(a); ok {
+		if y, ok := toTime(b); ok {
+			switch {
+			case x.Before(y):
+				return -1, true
+			case x.After(y):
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		return strings.Compare(x, y), ok
+	case bool:
+		y, ok := b.(bool)
+		if !ok || x == y {
+			return 0, ok
+		} else if y {
+			return -1, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int8:
+		return float64(t), true
+	case int16:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case uint:
+		return float64(t), true
+	case uint8:
+		return float64(t), true
+	case uint16:
+		return float64(t), true
+	case uint32:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toTime returns time.Time values and RFC 3339 strings as times.
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		tm, err := time.Parse(time.RFC3339, t)
+		return tm, err == nil
+	}
+	return time.Time{}, false
+}
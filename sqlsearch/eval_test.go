@@ -0,0 +1,140 @@
+package sqlsearch
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestEval(t *testing.T) {
+	records := []map[string]interface{}{
+		{"id": 1, "name": "Bob", "age": 30.0, "city": "Paris", "tags": []interface{}{"a", "b"}, "created_at": time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"id": 2, "name": "alice", "age": 25, "city": "Paris", "tags": []interface{}{"b"}, "created_at": "2019-01-01T00:00:00Z"},
+		{"id": 3, "name": "carol", "age": nil, "city": "Rome"},
+		{"id": 4, "name": "50% dave", "age": int64(41), "city": nil, "tags": []interface{}{}},
+	}
+	for _, c := range []struct {
+		params url.Values
+		ids    []int
+	}{
+		{url.Values{}, []int{1, 2, 3, 4}},
+		{url.Values{"q": {`{"age":{"$gt":26}}`}}, []int{1, 4}},
+		{url.Values{"q": {`{"age":{"$ne":30}}`}}, []int{2, 4}},
+		{url.Values{"q": {`{"age":{"$nin":[30]}}`}}, []int{2, 4}},
+		{url.Values{"q": {`{"$nor":[{"age":30}]}`}}, []int{2, 4}},
+		{url.Values{"q": {`{"age":null}`}}, []int{3}},
+		{url.Values{"q": {`{"name":"/B/"}`}}, []int{1}},
+		{url.Values{"q": {`{"name":"/0%/"}`}}, []int{4}},
+		{url.Values{"q": {`{"name":{"$like":"_li%"}}`}}, []int{2}},
+		{url.Values{"q": {`{"name":{"$like":"b%"}}`}}, nil},
+		{url.Values{"q": {`{"name":{"$ilike":"b%"}}`}}, []int{1}},
+		{url.Values{"q": {`{"age":{"$like":"4%"}}`}}, []int{4}},
+		{url.Values{"q": {`{"name":{"$regex":"^[a-c]"}}`}}, []int{2, 3}},
+		{url.Values{"q": {`{"name":{"$regex":"^b","$options":"i"}}`}}, []int{1}},
+		{url.Values{"q": {`{"$or":[{"city":"Rome"},{"age":{"$between":[20,26]}}]}`}}, []int{2, 3}},
+		{url.Values{"q": {`{"city":{"$not":{"$eq":"Paris"}}}`}}, []int{3}},
+		{url.Values{"q": {`{"created_at":{"$gte":"2018-06-01T00:00:00+08:00"}}`}}, []int{2}},
+		{url.Values{"q": {`{"tags":{"$all":["b"]}}`}}, []int{1, 2}},
+		{url.Values{"q": {`{"tags":{"$size":0}}`}}, []int{4}},
+		{url.Values{"q": {`{"tags":{"$elemMatch":{"$eq":"a"}}}`}}, []int{1}},
+		{url.Values{"sort": {`["age"]`}}, []int{3, 2, 1, 4}},
+		{url.Values{"sort": {`["city","-id"]`}, "limit": {"2"}, "page": {"2"}}, []int{1, 3}},
+	} {
+		q, err := Parse(c.params)
+		if err != nil {
+			t.Fatal(c.params, err)
+		}
+		rows, err := Eval(q, records)
+		if err != nil {
+			t.Fatal(c.params, err)
+		}
+		var ids []int
+		for _, r := range rows {
+			ids = append(ids, r["id"].(int))
+		}
+		if len(ids) != len(c.ids) {
+			t.Fatalf("%v: unexpected rows %v", c.params, ids)
+		}
+		for i := range ids {
+			if ids[i] != c.ids[i] {
+				t.Fatalf("%v: unexpected rows %v", c.params, ids)
+			}
+		}
+	}
+
+	q, _ := Parse(url.Values{"select": {`["$city"]`}, "sort": {`["city"]`}})
+	if rows, _ := Eval(q, records); len(rows) != 3 || rows[0]["city"] != nil || rows[2]["city"] != "Rome" || len(rows[1]) != 1 {
+		t.Fatal("unexpected projection", rows)
+	}
+	if rows, err := Eval(&Query{Offset: -1, Limit: -1}, records); err != nil || len(rows) != 0 {
+		t.Fatal("negative offsets and limits should count as 0", rows, err)
+	}
+	q, _ = Parse(url.Values{"q": {`{"name":{"$regex":"("},"age":{"$foo":1}}`}})
+	if _, err := Eval(q, nil); err == nil || len(err.(ValidationErrors)) != 2 {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestEvalDialect(t *testing.T) {
+	record := map[string]interface{}{"name": "Bob Été"}
+	for _, c := range []struct {
+		q       string
+		dialect []Dialect
+		want    bool
+	}{
+		{`{"name":"/bob/"}`, nil, false},
+		{`{"name":"/bob/"}`, []Dialect{Postgres}, false},
+		{`{"name":"/bob/"}`, []Dialect{SQLite}, true},
+		{`{"name":{"$startsWith":"bob été"}}`, []Dialect{SQLite}, false},
+		{`{"name":{"$like":"bob été"}}`, []Dialect{MySQL}, true},
+		{`{"name":{"$ilike":"BOB ÉTÉ"}}`, []Dialect{Postgres}, true},
+	} {
+		if ok, err := Match(c.q, record, c.dialect...); err != nil || ok != c.want {
+			t.Fatalf("%s %v: unexpected match %v %v", c.q, c.dialect, ok, err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	record := map[string]interface{}{
+		"name":  "bob",
+		"age":   30.0,
+		"user":  map[string]interface{}{"city": "Paris", "zip": nil},
+		"roles": []interface{}{"admin", "dev"},
+	}
+	for q, want := range map[string]bool{
+		`{}`:                                                 true,
+		`{"user.city":"Paris"}`:                              true,
+		`{"user.city":{"$in":["Rome"]}}`:                     false,
+		`{"user.zip":null,"user.nope":null}`:                 true,
+		`{"user.zip":{"$ne":"x"}}`:                           false,
+		`{"roles[1]":"dev","age":{"$lt":31}}`:                true,
+		`{"$or":[{"age":{"$gt":40}},{"missing":{"$gt":1}}]}`: false,
+		`{"$nor":[{"missing":{"$gt":1}}]}`:                   false,
+	} {
+		ok, err := Match(q, record)
+		if err != nil || ok != want {
+			t.Fatalf("%s: unexpected match %v %v", q, ok, err)
+		}
+	}
+	for _, q := range []string{`{"a":`, `{"$xor":{"a":1}}`, `{"a":{"$regex":"("}}`} {
+		if _, err := Match(q, record); err == nil {
+			t.Fatal("expect an error for", q)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	records := []map[string]interface{}{
+		{"name": "a", "user": map[string]interface{}{"age": 20.0}},
+		{"name": "b", "user": map[string]interface{}{"age": 40.0}},
+		{"name": "c"},
+	}
+	rows, err := Apply(url.Values{"q": {`{"user.age":{"$gte":10}}`}, "sort": {`["-user.age"]`}, "select": {`["name","user.age"]`}}, records)
+	if err != nil || len(rows) != 2 || rows[0]["name"] != "b" || rows[0]["user.age"] != 40.0 || len(rows[0]) != 2 {
+		t.Fatal("unexpected rows", rows, err)
+	}
+	if _, err := Apply(url.Values{"limit": {"x"}}, records); err == nil {
+		t.Fatal("expect an error for an invalid limit")
+	}
+}
@@ -0,0 +1,266 @@
+package sqlsearch
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MongoQuery is a Query rendered for the MongoDB drivers.
+type MongoQuery struct {
+	// Filter is the filter document, {} without a filter.
+	Filter map[string]interface{}
+	// Sort lists the sort keys in order, each converts to a bson.E.
+	Sort       []MongoElem
+	Projection map[string]interface{}
+	// Distinct lists the fields selected with "$name", for Collection.Distinct.
+	Distinct []string
+	Skip     int64
+	Limit    int64
+}
+
+// MongoElem is a key of an ordered document such as a bson.D.
+type MongoElem struct {
+	Key   string
+	Value interface{}
+}
+
+// ToMongo renders q as a MongoDB query. RFC 3339 strings compared with operators become time.Time in loc
+// like they do in SQL, "/text/" and the LIKE operators become $regex, $between becomes $gte and $lte
+// and $null becomes a comparison with null.
+//
+//	mq, err := sqlsearch.ToMongo(query, time.Local)
+//	opts := options.Find().SetSkip(mq.Skip).SetLimit(mq.Limit).SetProjection(mq.Projection)
+//	cur, err := coll.Find(ctx, mq.Filter, opts)
+func ToMongo(q *Query, loc *time.Location) (*MongoQuery, error) {
+	m := &mongoWriter{loc: loc}
+	mq := &MongoQuery{Filter: map[string]interface{}{}, Skip: int64(q.Offset), Limit: int64(q.Limit)}
+	if q.Filter != nil {
+		mq.Filter = m.group(q.Filter)
+	}
+	for _, s := range q.Sort {
+		order := 1
+		if s.Desc {
+			order = -1
+		}
+		mq.Sort = append(mq.Sort, MongoElem{Key: s.Field, Value: order})
+	}
+	for _, p := range q.Select {
+		if p.Distinct {
+			mq.Distinct = append(mq.Distinct, p.Field)
+			continue
+		}
+		if mq.Projection == nil {
+			mq.Projection = map[string]interface{}{}
+		}
+		mq.Projection[p.Field] = 1
+	}
+	if len(m.errs) > 0 {
+		return nil, m.errs
+	}
+	return mq, nil
+}
+
+type mongoWriter struct {
+	loc  *time.Location
+	errs ValidationErrors
+}
+
+func (m *mongoWriter) invalid(field, op, msg string) {
+	m.errs = append(m.errs, &ValidationError{Param: "q", Field: field, Operator: op, Message: msg})
+}
+
+// group returns the document of g, the members of an "$and" are merged when they don't overlap.
+func (m *mongoWriter) group(g *Group) map[string]interface{} {
+	docs := make([]interface{}, 0, len(g.Nodes))
+	for _, n := range g.Nodes {
+		switch t := n.(type) {
+		case *Group:
+			docs = append(docs, m.group(t))
+		case *Cond:
+			docs = append(docs, map[string]interface{}{t.Field: m.cond(t)})
+		}
+	}
+	switch g.Op {
+	case "$or", "$nor":
+		return map[string]interface{}{g.Op: docs}
+	case "$and":
+		merged := map[string]interface{}{}
+		for _, doc := range docs {
+			if !mergeDoc(merged, doc.(map[string]interface{})) {
+				return map[string]interface{}{"$and": docs}
+			}
+		}
+		return merged
+	}
+	m.invalid("", g.Op, "unknown operator")
+	return map[string]interface{}{}
+}
+
+// mergeDoc adds the members of src to dst, merging operator documents of the same field.
+// It returns false if they conflict, dst is then partially merged.
+func mergeDoc(dst, src map[string]interface{}) bool {
+	for k, v := range src {
+		cur, ok := dst[k]
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		a, aok := cur.(map[string]interface{})
+		b, bok := v.(map[string]interface{})
+		if !aok || !bok || strings.HasPrefix(k, "$") || !isOperatorDoc(a) || !isOperatorDoc(b) {
+			return false
+		}
+		merged := make(map[string]interface{}, len(a)+len(b))
+		for op, v := range a {
+			merged[op] = v
+		}
+		for op, v := range b {
+			if _, dup := merged[op]; dup {
+				return false
+			}
+			merged[op] = v
+		}
+		dst[k] = merged
+	}
+	return true
+}
+
+func isOperatorDoc(doc map[string]interface{}) bool {
+	for k := range doc {
+		if !strings.HasPrefix(k, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// cond returns the value of a field in the filter, a plain value or an operator document.
+func (m *mongoWriter) cond(c *Cond) interface{} {
+	if c.Op == "" {
+		if s, ok := c.Value.(string); ok && len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+			return map[string]interface{}{"$regex": regexp.QuoteMeta(s[1 : len(s)-1])}
+		}
+		return m.value(c.Value)
+	}
+	return m.ops(c.Field, []*Cond{c})
+}
+
+// ops returns the operator document of conds.
+func (m *mongoWriter) ops(field string, conds []*Cond) map[string]interface{} {
+	doc := map[string]interface{}{}
+	for _, c := range conds {
+		op, v := c.Op, c.Value
+		grp, ok := mg2SqlGroup[op]
+		if !ok || grp.Type == opGroup {
+			m.invalid(field, op, "unknown operator")
+			continue
+		}
+		switch grp.Type {
+		case opCompare:
+			doc[op] = m.value(v)
+		case opList:
+			list, ok := v.([]interface{})
+			if !ok {
+				m.invalid(field, op, "expects an array")
+				continue
+			}
+			values := make([]interface{}, len(list))
+			for i, e := range list {
+				values[i] = m.value(e)
+			}
+			doc[op] = values
+		case opExists, opNull:
+			b, ok := v.(bool)
+			if !ok {
+				m.invalid(field, op, "expects a boolean")
+				continue
+			}
+			if op == "$exists" {
+				doc[op] = b
+			} else if b {
+				doc["$eq"] = nil
+			} else {
+				doc["$ne"] = nil
+			}
+		case opLike:
+			s, ok := v.(string)
+			if !ok {
+				m.invalid(field, op, "expects a string")
+				continue
+			}
+			switch op {
+			case "$like":
+				doc["$regex"] = likeRegexp(s)
+			case "$ilike":
+				doc["$regex"], doc["$options"] = likeRegexp(s), "i"
+			case "$startsWith":
+				doc["$regex"] = "^" + regexp.QuoteMeta(s)
+			case "$endsWith":
+				doc["$regex"] = regexp.QuoteMeta(s) + "$"
+			}
+		case opRegexp:
+			doc[op] = v
+			if c.Options != "" {
+				doc["$options"] = c.Options
+			}
+		case opOptions:
+			m.invalid(field, op, "needs $regex")
+		case opBetween:
+			if bounds, ok := v.([]interface{}); ok && len(bounds) == 2 {
+				doc["$gte"], doc["$lte"] = m.value(bounds[0]), m.value(bounds[1])
+			} else {
+				m.invalid(field, op, "expects an array of 2 values")
+			}
+		case opNot, opArray:
+			if op == "$size" {
+				if n, ok := v.(float64); !ok || n < 0 || n != math.Trunc(n) {
+					m.invalid(field, op, "expects a positive integer")
+					continue
+				}
+			}
+			if conds, ok := v.([]*Cond); ok {
+				doc[op] = m.ops(field, conds)
+			} else if s, ok := v.(string); ok && op == "$not" && len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+				doc[op] = map[string]interface{}{"$regex": regexp.QuoteMeta(s[1 : len(s)-1])}
+			} else {
+				doc[op] = m.value(v)
+			}
+		default:
+			doc[op] = v
+		}
+	}
+	return doc
+}
+
+// value converts RFC 3339 strings to time.Time.
+func (m *mongoWriter) value(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		if tm, err := time.Parse(time.RFC3339, s); err == nil {
+			if m.loc != nil {
+				tm = tm.In(m.loc)
+			}
+			return tm
+		}
+	}
+	return v
+}
+
+// likeRegexp converts a LIKE pattern to an anchored regular expression.
+func likeRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package sqlsearch
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestToMongo(t *testing.T) {
+	for _, c := range []struct {
+		q      string
+		filter string
+	}{
+		{`{"name":"bob","age":{"$gt":1},"$or":[{"age":{"$lt":5}},{"tags":{"$all":["a"]}}]}`,
+			`{"$or":[{"age":{"$lt":5}},{"tags":{"$all":["a"]}}],"age":{"$gt":1},"name":"bob"}`},
+		{`{"age":{"$gt":1},"$and":[{"age":{"$lt":5}}]}`, `{"age":{"$gt":1,"$lt":5}}`},
+		{`{"$or":[{"a":1},{"b":2}],"$and":[{"$or":[{"c":3},{"d":4}]}]}`,
+			`{"$and":[{"$or":[{"a":1},{"b":2}]},{"$or":[{"c":3},{"d":4}]}]}`},
+		{`{"desc":"/1+1/","name":{"$ilike":"b_b%"},"code":{"$startsWith":"a."}}`,
+			`{"code":{"$regex":"^a\\."},"desc":{"$regex":"1\\+1"},"name":{"$options":"i","$regex":"^b.b.*$"}}`},
+		{`{"age":{"$between":[1,5]},"x":{"$null":true},"y":{"$not":{"$in":[1]}},"s":{"$elemMatch":{"$gt":1}}}`,
+			`{"age":{"$gte":1,"$lte":5},"s":{"$elemMatch":{"$gt":1}},"x":{"$eq":null},"y":{"$not":{"$in":[1]}}}`},
+		{`{"name":{"$regex":"^b","$options":"i"},"$nor":[{"a":1}]}`, `{"$nor":[{"a":1}],"name":{"$options":"i","$regex":"^b"}}`},
+	} {
+		q, err := Parse(url.Values{"q": {c.q}})
+		if err != nil {
+			t.Fatal(c.q, err)
+		}
+		mq, err := ToMongo(q, nil)
+		if err != nil {
+			t.Fatal(c.q, err)
+		}
+		if b, _ := json.Marshal(mq.Filter); string(b) != c.filter {
+			t.Fatalf("%s: unexpected filter %s", c.q, b)
+		}
+	}
+
+	q, _ := Parse(url.Values{
+		"q":      {`{"created_at":{"$gte":"2010-05-18T00:00:00+08:00"}}`},
+		"sort":   {`["-created_at","name"]`},
+		"select": {`["name","$city"]`},
+		"limit":  {"10"},
+		"page":   {"2"},
+	})
+	mq, err := ToMongo(q, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tm, ok := mq.Filter["created_at"].(map[string]interface{})["$gte"].(time.Time); !ok || !tm.Equal(time.Date(2010, 5, 17, 16, 0, 0, 0, time.UTC)) {
+		t.Fatal("times are not converted", mq.Filter)
+	}
+	if len(mq.Sort) != 2 || mq.Sort[0] != (MongoElem{Key: "created_at", Value: -1}) || mq.Projection["name"] != 1 ||
+		len(mq.Distinct) != 1 || mq.Skip != 10 || mq.Limit != 10 {
+		t.Fatal("unexpected query", mq)
+	}
+
+	q, _ = Parse(url.Values{"q": {`{"a":{"$foo":1},"b":{"$options":"i"},"c":{"$like":1},"d":{"$exists":1},"e":{"$null":"x"},"f":{"$size":-1}}`}})
+	if _, err := ToMongo(q, nil); err == nil || err.Error() != `sqlsearch: q: field "a": $foo: unknown operator; sqlsearch: q: field "b": $options: needs $regex; sqlsearch: q: field "c": $like: expects a string; `+
+		`sqlsearch: q: field "d": $exists: expects a boolean; sqlsearch: q: field "e": $null: expects a boolean; sqlsearch: q: field "f": $size: expects a positive integer` {
+		t.Fatal("unexpected error", err)
+	}
+}
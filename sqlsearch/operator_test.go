@@ -0,0 +1,82 @@
+package sqlsearch
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestOperators(t *testing.T) {
+	schema := NewSchema(
+		Field{Name: "name", Type: TypeString},
+		Field{Name: "age", Type: TypeInt},
+		Field{Name: "tags", Type: TypeString},
+		Field{Name: "scores", Type: TypeInt},
+		Field{Name: "attrs", Type: TypeJSON},
+	)
+	for _, c := range []struct {
+		q       string
+		dialect Dialect
+		where   string
+	}{
+		{`{"name":{"$not":{"$eq":"a","$like":"b%"}}}`, nil, "NOT (name = 'a' AND name LIKE 'b%')"},
+		{`{"name":{"$not":"/a/"}}`, nil, "NOT (name LIKE '%a%')"},
+		{`{"name":"\\' OR 1=1 -- "}`, nil, `name = '\\'' OR 1=1 -- '`},
+		{`{"$nor":{"name":"a","age":1}}`, nil, "NOT (name = 'a' OR age = 1)"},
+		{`{"name":{"$regex":"^a.*"}}`, nil, "REGEXP_LIKE(name, '^a.*', 'c')"},
+		{`{"name":{"$regex":"^a","$options":"i"}}`, Postgres, `"name" ~* '^a'`},
+		{`{"name":{"$regex":"^a","$options":"i"}}`, SQLite, `"name" REGEXP '(?i)' || '^a'`},
+		{`{"name":{"$ilike":"A%"}}`, nil, "LOWER(name) LIKE LOWER('A%')"},
+		{`{"name":{"$ilike":"A%"}}`, Postgres, `"name" ILIKE 'A%'`},
+		{`{"name":{"$startsWith":"5%"}}`, nil, "name LIKE '5!%%' ESCAPE '!'"},
+		{`{"name":{"$endsWith":"x"}}`, MySQL, "`name` LIKE '%x' ESCAPE '!'"},
+		{`{"age":{"$between":[18,30]}}`, nil, "age BETWEEN 18 AND 30"},
+		{`{"age":{"$null":true},"name":{"$null":false}}`, nil, "age IS NULL AND name IS NOT NULL"},
+		{`{"age":{"$eq":null},"name":{"$ne":null}}`, nil, "age IS NULL AND name IS NOT NULL"},
+		{`{"tags":{"$all":["a","b"]},"scores":{"$size":2}}`, Postgres, `"tags" @> ARRAY['a','b'] AND cardinality("scores") = 2`},
+		{`{"scores":{"$elemMatch":{"$gte":80,"$lt":"90"}}}`, Postgres, `EXISTS (SELECT 1 FROM unnest("scores") AS _e WHERE _e >= 80 AND _e < 90)`},
+		{`{"attrs.color":"red","attrs.size.w":{"$gt":2}}`, nil, "JSON_UNQUOTE(JSON_EXTRACT(attrs, '$.color')) = 'red' AND JSON_EXTRACT(attrs, '$.size.w') > 2"},
+		{`{"attrs.color":"red","attrs.size.w":{"$gt":2}}`, Postgres, `"attrs" #>> '{color}' = 'red' AND ("attrs" #>> '{size,w}')::numeric > 2`},
+		{`{"attrs.color":"red","attrs.size.w":{"$gt":2}}`, SQLite, `json_extract("attrs", '$.color') = 'red' AND json_extract("attrs", '$.size.w') > 2`},
+		{`{"attrs.color":"red","attrs.size.w":{"$gt":2}}`, SQLServer, `JSON_VALUE([attrs], '$.color') = N'red' AND CAST(JSON_VALUE([attrs], '$.size.w') AS FLOAT) > 2`},
+	} {
+		q := New(url.Values{"q": {c.q}}, nil, nil, c.dialect)
+		q.Schema = schema
+		if err := q.Validate(); err != nil {
+			t.Fatalf("%s: %v", c.q, err)
+		}
+		if sq := q.ToSql(); sq.Where != c.where {
+			t.Fatalf("%s: unexpected where %s", c.q, sq.Where)
+		}
+	}
+
+	if sq := New(url.Values{"sort": {`["id; DROP TABLE x"]`}}, nil, nil, Postgres).ToSql(); sq.Order != "" {
+		t.Fatal("invalid field names should be dropped", sq.Order)
+	}
+
+	where, args, err := Q2SqlArgs(`{"name":{"$startsWith":"a_","$regex":"b"},"age":{"$between":[1,2]}}`, nil, nil, DollarPlaceholder)
+	if err != nil || where != "name LIKE $1 ESCAPE '!' AND REGEXP_LIKE(name, $2, 'c') AND age BETWEEN $3 AND $4" || len(args) != 4 || args[0] != "a!_%" {
+		t.Fatal("unexpected query", where, args, err)
+	}
+
+	for _, c := range []struct {
+		q       string
+		dialect Dialect
+		err     string
+	}{
+		{`{"name":{"$foo":1}}`, nil, `sqlsearch: q: field "name": $foo: unknown operator`},
+		{`{"$foo":{"name":1}}`, nil, `sqlsearch: q: $foo: unknown operator`},
+		{`{"name":{"$options":"i"}}`, nil, `sqlsearch: q: field "name": $options: needs $regex`},
+		{`{"name":{"$regex":"a","$options":"m"}}`, nil, `sqlsearch: q: field "name": $options: only the i option is supported`},
+		{`{"name":{"$regex":"a"}}`, SQLServer, `sqlsearch: q: field "name": $regex: not supported by the dialect`},
+		{`{"tags":{"$size":1}}`, MySQL, `sqlsearch: q: field "tags": $size: not supported by the dialect`},
+		{`{"age":{"$between":[1]}}`, nil, `sqlsearch: q: field "age": $between: expects an array of 2 values`},
+		{`{"age":{"$null":1}}`, nil, `sqlsearch: q: field "age": $null: expects a boolean`},
+		{`{"name.x":"a"}`, nil, `sqlsearch: q: field "name.x": unknown field`},
+	} {
+		q := New(url.Values{"q": {c.q}}, nil, nil, c.dialect)
+		q.Schema = schema
+		if err := q.Validate(); err == nil || err.Error() != c.err {
+			t.Fatalf("%s: unexpected error %v", c.q, err)
+		}
+	}
+}
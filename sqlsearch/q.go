@@ -1,145 +1,649 @@
 package sqlsearch
 
 import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
 	"strings"
 	"time"
-	"strconv"
-	"log"
-	"encoding/json"
+	"unicode"
+)
+
+// Operator kinds of mg2SqlGroup.
+const (
+	opCompare = iota
+	opList
+	opExists
+	opGroup
+	opNull
+	opLike
+	opRegexp
+	opOptions
+	opBetween
+	opNot
+	opArray
 )
 
 var mg2SqlGroup = map[string]struct {
 	Type int
 	Sql  string
 }{
-	"$eq":     {0, "="},
-	"$ne":     {0, "<>"},
-	"$gt":     {0, ">"},
-	"$gte":    {0, ">="},
-	"$lt":     {0, "<"},
-	"$lte":    {0, "<="},
-	"$in":     {1, "IN"},
-	"$nin":    {1, "NOT IN"},
-	"$exists": {2, "IS NOT NULL"},
-	"$and": {3, "AND"},
-	"$or": {3, "OR"},
+	"$eq":         {opCompare, "="},
+	"$ne":         {opCompare, "<>"},
+	"$gt":         {opCompare, ">"},
+	"$gte":        {opCompare, ">="},
+	"$lt":         {opCompare, "<"},
+	"$lte":        {opCompare, "<="},
+	"$in":         {opList, "IN"},
+	"$nin":        {opList, "NOT IN"},
+	"$exists":     {opExists, "IS NOT NULL"},
+	"$and":        {opGroup, "AND"},
+	"$or":         {opGroup, "OR"},
+	"$nor":        {opGroup, "OR"},
+	"$null":       {opNull, "IS NULL"},
+	"$like":       {opLike, "LIKE"},
+	"$ilike":      {opLike, "ILIKE"},
+	"$startsWith": {opLike, "LIKE"},
+	"$endsWith":   {opLike, "LIKE"},
+	"$regex":      {opRegexp, "REGEXP"},
+	"$options":    {opOptions, ""},
+	"$between":    {opBetween, "BETWEEN"},
+	"$not":        {opNot, "NOT"},
+	"$all":        {opArray, "@>"},
+	"$size":       {opArray, "cardinality"},
+	"$elemMatch":  {opArray, "EXISTS"},
 }
 
+// Placeholder formats the n-th bind parameter of a query, n starts at 1.
+type Placeholder func(n int) string
 
+// Placeholder styles of the common database drivers.
+var (
+	// QuestionPlaceholder is used by MySQL and SQLite: ?
+	QuestionPlaceholder Placeholder = func(n int) string { return "?" }
+	// DollarPlaceholder is used by PostgreSQL: $1
+	DollarPlaceholder Placeholder = func(n int) string { return "$" + strconv.Itoa(n) }
+	// AtPlaceholder is used by SQL Server: @p1
+	AtPlaceholder Placeholder = func(n int) string { return "@p" + strconv.Itoa(n) }
+)
+
+// LikeEscape is the escape character of the LIKE patterns written by Q2SqlArgs.
+const LikeEscape = "!"
+
+// Q2Sql converts a MongoDB like query to a WHERE clause with the values inlined as literals,
+// which are only protected by quote doubling. Prefer Q2SqlArgs for user provided queries.
+// Besides the comparisons, $in, $nin, $exists, $and and $or, it supports $not, $nor, $regex with
+// the "i" $options, $like, $ilike, $startsWith, $endsWith, $between and $null, and with Postgres
+// $all, $size and $elemMatch on array columns. Dotted names query the properties of TypeJSON fields.
 func Q2Sql(queryStr string, timeLocal *time.Location, getKey func(k string) string) string {
-	var query OrderedMap
-	if err := json.Unmarshal([]byte(queryStr), &query); err != nil {
+	return newSqlWriter(timeLocal, getKey, nil).q(queryStr)
+}
+
+// Q2SqlArgs converts a MongoDB like query to a WHERE clause with placeholders and returns
+// the arguments to pass with it, so values never become part of the SQL text. A nil placeholder
+// uses QuestionPlaceholder. RFC 3339 strings compared with operators become time.Time in timeLocal,
+// and "/text/" matches text anywhere with LIKE, escaping the wildcards of text with LikeEscape.
+// Field names must be identifiers such as "name" or "user.name", unknown operators are errors.
+// The error is a ValidationErrors listing every problem found.
+//
+//	where, args, err := sqlsearch.Q2SqlArgs(`{"name":"/o'k/","age":{"$gt":18}}`, time.Local, nil, sqlsearch.DollarPlaceholder)
+//	// name LIKE $1 ESCAPE '!' AND age > $2, [%o'k% 18]
+func Q2SqlArgs(queryStr string, timeLocal *time.Location, getKey func(k string) string, placeholder Placeholder) (string, []interface{}, error) {
+	if placeholder == nil {
+		placeholder = QuestionPlaceholder
+	}
+	w := newSqlWriter(timeLocal, getKey, placeholder)
+	where := w.q(queryStr)
+	if err := w.err(); err != nil {
+		return "", nil, err
+	}
+	return where, w.args, nil
+}
+
+// sqlWriter renders queries either with inlined literals, the output of ToSql,
+// or with placeholders collecting the arguments when placeholder is set.
+type sqlWriter struct {
+	timeLocal   *time.Location
+	getKey      func(k string) string
+	placeholder Placeholder
+	schema      *Schema
+	dialect     Dialect
+	args        []interface{}
+
+	// param is the URL parameter being rendered, for the validation errors.
+	param string
+	errs  ValidationErrors
+}
+
+func newSqlWriter(timeLocal *time.Location, getKey func(k string) string, placeholder Placeholder) *sqlWriter {
+	if getKey == nil {
+		getKey = func(key string) string {
+			return key
+		}
+	}
+	return &sqlWriter{timeLocal: timeLocal, getKey: getKey, placeholder: placeholder, param: "q"}
+}
+
+// invalid records a validation error, the literal output skips invalid clauses like it always did.
+// An error repeated by the operators of a field is recorded once.
+func (w *sqlWriter) invalid(field, op, format string, a ...interface{}) {
+	e := &ValidationError{Param: w.param, Field: field, Operator: op, Message: fmt.Sprintf(format, a...)}
+	for _, err := range w.errs {
+		if *err == *e {
+			return
+		}
+	}
+	w.errs = append(w.errs, e)
+}
+
+func (w *sqlWriter) err() error {
+	if len(w.errs) == 0 {
+		return nil
+	}
+	return w.errs
+}
+
+// q renders the JSON of the q parameter.
+func (w *sqlWriter) q(queryStr string) string {
+	g, err := parseQ(queryStr)
+	if err != nil {
+		err.Param = w.param
+		w.errs = append(w.errs, err)
 		return ""
 	}
-	return map2Sql(query, "AND", timeLocal, getKey)
+	where, _ := w.group(g)
+	return where
 }
 
+// column is a field resolved to SQL, path is set for the properties of TypeJSON fields.
+type column struct {
+	name  string
+	field *Field
+	sql   string
+	path  []string
+}
 
-func map2Sql(query OrderedMap, condition string, timeLocal *time.Location, getKey func(k string) string) (ret string) {
-	var where string
-	for _, key := range query.Keys() {
-		value := query.MustGet(key)
-		if strings.HasPrefix(key, "$") {
-			if v, ok := value.(OrderedMap); ok {
-				if val, ok := mg2SqlGroup[key]; ok {
+// field resolves a field of q, ok is false if it is rejected. Without a schema,
+// placeholders are only written for identifier fields.
+func (w *sqlWriter) field(name string) (c *column, ok bool) {
+	if w.schema != nil {
+		f, ok := w.schema.Field(name)
+		var path []string
+		if !ok {
+			if f, path = w.schema.jsonField(name); f == nil {
+				w.invalid(name, "", "unknown field")
+				return nil, false
+			}
+		}
+		col := f.Column
+		if col == "" {
+			col = w.getKey(f.Name)
+		}
+		return &column{name: name, field: f, sql: w.ident(col), path: path}, true
+	}
+	if (w.placeholder != nil || w.dialect != nil) && !isIdentifier(name) {
+		w.invalid(name, "", "invalid field name")
+		return nil, false
+	}
+	return &column{name: name, sql: w.ident(w.getKey(name))}, true
+}
 
-					var extra = map2Sql(v, val.Sql, timeLocal, getKey)
+// ident quotes col with the dialect when it is a column name, expressions are left as they are.
+func (w *sqlWriter) ident(col string) string {
+	if w.dialect == nil || !isIdentifier(col) {
+		return col
+	}
+	parts := strings.Split(col, ".")
+	for i, p := range parts {
+		parts[i] = w.dialect.QuoteIdent(p)
+	}
+	return strings.Join(parts, ".")
+}
 
-					if where == "" {
-						where = extra
-					} else {
-						where += " " + condition + " (" + extra + ")"
-					}
+// colSQL returns the SQL of c, JSON properties compared with numbers are read as numbers.
+func (w *sqlWriter) colSQL(c *column, v interface{}) string {
+	if c.path == nil {
+		return c.sql
+	}
+	if list, ok := v.([]interface{}); ok && len(list) > 0 {
+		v = list[0]
+	}
+	var numeric bool
+	switch v.(type) {
+	case float64, int, int64:
+		numeric = true
+	}
+	jd, ok := w.dialect.(JSONDialect)
+	if w.dialect == nil {
+		jd, ok = MySQL.(JSONDialect)
+	}
+	if !ok {
+		w.invalid(c.name, "", "JSON properties are not supported by the dialect")
+		return c.sql
+	}
+	return jd.JSONPath(c.sql, c.path, numeric)
+}
+
+// column returns the column of a field used in sort or select, checking the schema permits it.
+func (w *sqlWriter) column(name, param string) (string, bool) {
+	c, ok := w.field(name)
+	if !ok {
+		return "", false
+	}
+	if f := c.field; f != nil && ((param == "sort" && !f.Sortable) || (param == "select" && !f.Selectable)) {
+		w.invalid(name, "", "not allowed in %s", param)
+		return "", false
+	}
+	return w.colSQL(c, nil), true
+}
+
+// allows reports whether op may be used on c.
+func (w *sqlWriter) allows(c *column, op string) bool {
+	if c.field != nil && !c.field.allows(op) {
+		w.invalid(c.name, op, "operator not allowed")
+		return false
+	}
+	return true
+}
+
+// value writes v as a literal or a placeholder, it returns "" for invalid values.
+// Values of typed fields are coerced to the field type, RFC 3339 strings of
+// other fields are converted to timeLocal when parseTime is set.
+func (w *sqlWriter) value(c *column, op string, v interface{}, parseTime bool) string {
+	if f := c.field; f != nil && f.Type != TypeAny && f.Type != TypeJSON {
+		cv, err := coerce(f.Type, v, w.timeLocal)
+		if err != nil {
+			w.invalid(c.name, op, "%v", err)
+			return ""
+		}
+		v, parseTime = cv, false
+	}
+	if w.placeholder == nil {
+		if w.dialect != nil {
+			return w.literal(c.name, op, v, parseTime)
+		}
+		if !parseTime {
+			return toSqlString(v, nil)
+		}
+		return toSqlString(v, w.timeLocal)
+	}
+	switch t := v.(type) {
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) < 1<<53 {
+			v = int64(t)
+		}
+	case string:
+		if parseTime {
+			if tm, err := time.Parse(time.RFC3339, t); err == nil {
+				if w.timeLocal != nil {
+					tm = tm.In(w.timeLocal)
 				}
+				v = tm
 			}
-			continue
 		}
-		if stringValue, ok := value.(string); ok {
-			if strings.HasPrefix(stringValue, "/") && strings.HasSuffix(stringValue, "/") {
-				v := stringValue[1:len(stringValue)-1]
-				where = appendWhere(where,condition, getKey(key)+" LIKE '%"+v+"%'")
-			} else {
-				if tm, err := time.Parse(time.RFC3339, value.(string)); err == nil {
-					where = appendWhere(where,  condition, getKey(key)+" = '"+tm.In(timeLocal).Format("2006-01-02 15:04:05")+"'")
-				} else {
-					where = appendWhere(where,  condition, getKey(key)+" = '"+stringValue+"'")
-				}
+	case bool, int, int64, time.Time:
+	default:
+		w.invalid(c.name, op, "unsupported value %v", v)
+		return ""
+	}
+	w.args = append(w.args, v)
+	return w.placeholder(len(w.args))
+}
+
+// str writes a string as a literal or a placeholder, without coercion or time parsing.
+func (w *sqlWriter) str(name, op, s string) string {
+	return w.value(&column{name: name}, op, s, false)
+}
+
+// literal writes v with the dialect like toSqlString does without one.
+func (w *sqlWriter) literal(name, op string, v interface{}, parseTime bool) string {
+	switch t := v.(type) {
+	case int:
+		return strconv.Itoa(t)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return w.dialect.Bool(t)
+	case time.Time:
+		return w.dialect.Time(t)
+	case string:
+		if parseTime && w.timeLocal != nil {
+			if tm, err := time.Parse(time.RFC3339, t); err == nil {
+				return w.dialect.Time(tm.In(w.timeLocal))
 			}
-		} else if mapValue, ok := value.(OrderedMap); ok {
-			for _, k := range mapValue.Keys() {
-				v := mapValue.MustGet(k)
-				//凡是以"$"开头是字段筛选条件，添加对日期取值的操作
-				if strings.HasPrefix(k, "$") {
-					grp := mg2SqlGroup[k]
-					switch grp.Type {
-					case 0:
-						conValue := toSqlString(v, timeLocal)
-						if conValue != "" {
-							where = appendWhere(where,  condition, getKey(key)+" "+grp.Sql+" "+conValue)
-						}
-					case 1:
-						var inValue string
-						if slices, ok := v.([]interface{}); ok && len(slices) > 0 {
-							for i, v := range slices {
-								if i > 0 {
-									inValue += ","
-								}
-								inValue += toSqlString(v, nil)
-							}
-						}
-						if inValue != "" {
-							where = appendWhere(where,  condition, getKey(key)+" "+grp.Sql+" ("+inValue+")")
-						}
-					case 2:
-						if k == "$exists" {
-							if exists, ok := v.(bool); ok {
-								if exists {
-									where = appendWhere(where,  condition, getKey(key)+" "+grp.Sql)
-								} else {
-									where = appendWhere(where,  condition, getKey(key)+" IS NULL")
-								}
-							}
-						}
-					default:
-						//DO NOTING
-					}
-				}
+		}
+		return w.dialect.QuoteString(t)
+	}
+	w.invalid(name, op, "unsupported value %v", v)
+	return ""
+}
+
+// like returns the LIKE condition of col matching s anywhere. Without a dialect,
+// literals keep the wildcards of s like ToSql always did.
+func (w *sqlWriter) like(c *column, s string) string {
+	col := w.colSQL(c, s)
+	if w.placeholder == nil && w.dialect == nil {
+		return col + " LIKE " + MySQL.QuoteString("%"+s+"%")
+	}
+	return col + " LIKE " + w.str(c.name, "$like", "%"+w.escapeLike(s)+"%") + " ESCAPE '" + LikeEscape + "'"
+}
+
+// group returns the conditions of g, compound reports whether they need parentheses
+// next to other conditions.
+func (w *sqlWriter) group(g *Group) (where string, compound bool) {
+	grp, ok := mg2SqlGroup[g.Op]
+	if !ok || grp.Type != opGroup {
+		w.invalid("", g.Op, "unknown operator")
+		return "", false
+	}
+	var conds []string
+	var compounds []bool
+	for _, n := range g.Nodes {
+		var cond string
+		var paren bool
+		switch t := n.(type) {
+		case *Group:
+			cond, paren = w.group(t)
+			paren = paren && !(t.Op == "$and" && g.Op == "$and")
+		case *Cond:
+			cond = w.cond(t)
+		}
+		if cond != "" {
+			conds = append(conds, cond)
+			compounds = append(compounds, paren)
+		}
+	}
+	if len(conds) > 1 {
+		for i, paren := range compounds {
+			if paren {
+				conds[i] = "(" + conds[i] + ")"
 			}
-		} else {
-			where = appendWhere(where, condition, getKey(key)+" = "+toSqlString(value, nil))
 		}
 	}
+	where = strings.Join(conds, " "+grp.Sql+" ")
+	if g.Op == "$nor" && where != "" {
+		return "NOT (" + where + ")", false
+	}
+	return where, len(conds) > 1
+}
 
-	return where
+// cond returns the condition of a field.
+func (w *sqlWriter) cond(cond *Cond) string {
+	c, ok := w.field(cond.Field)
+	if !ok {
+		return ""
+	}
+	if cond.Op != "" {
+		return w.operator(c, cond)
+	}
+	switch v := cond.Value.(type) {
+	case string:
+		if len(v) >= 2 && strings.HasPrefix(v, "/") && strings.HasSuffix(v, "/") {
+			if w.allows(c, "$like") {
+				return w.like(c, v[1:len(v)-1])
+			}
+			return ""
+		}
+		return w.operator(c, &Cond{Field: cond.Field, Op: "$eq", Value: v})
+	case nil:
+		if w.allows(c, "$eq") {
+			return w.colSQL(c, nil) + " IS NULL"
+		}
+	default:
+		if w.allows(c, "$eq") {
+			if val := w.value(c, "$eq", v, false); val != "" {
+				return w.colSQL(c, v) + " = " + val
+			}
+		}
+	}
+	return ""
+}
+
+// operators returns the conditions of conds on c joined by AND.
+func (w *sqlWriter) operators(c *column, conds []*Cond) string {
+	var where []string
+	for _, cond := range conds {
+		if s := w.operator(c, cond); s != "" {
+			where = append(where, s)
+		}
+	}
+	return strings.Join(where, " AND ")
+}
+
+// operator returns the condition of a field operator such as {"$gt": 1}, "" if there is none.
+func (w *sqlWriter) operator(c *column, cond *Cond) string {
+	op, v := cond.Op, cond.Value
+	grp, ok := mg2SqlGroup[op]
+	if !ok || grp.Type == opGroup {
+		w.invalid(c.name, op, "unknown operator")
+		return ""
+	}
+	if grp.Type == opOptions {
+		w.invalid(c.name, op, "needs $regex")
+		return ""
+	}
+	if !w.allows(c, op) {
+		return ""
+	}
+	col := w.colSQL(c, v)
+	switch grp.Type {
+	case opCompare:
+		if v == nil && op == "$eq" {
+			return col + " IS NULL"
+		} else if v == nil && op == "$ne" {
+			return col + " IS NOT NULL"
+		}
+		if conValue := w.value(c, op, v, true); conValue != "" {
+			return col + " " + grp.Sql + " " + conValue
+		}
+	case opList:
+		slices, ok := v.([]interface{})
+		if !ok {
+			w.invalid(c.name, op, "expects an array")
+			return ""
+		}
+		if len(slices) == 0 {
+			if op == "$in" {
+				return "1 = 0"
+			}
+			return ""
+		}
+		if values, ok := w.values(c, op, slices); ok {
+			return col + " " + grp.Sql + " (" + strings.Join(values, ",") + ")"
+		}
+	case opExists, opNull:
+		b, ok := v.(bool)
+		if !ok {
+			w.invalid(c.name, op, "expects a boolean")
+			return ""
+		}
+		if b == (op == "$exists") {
+			return col + " IS NOT NULL"
+		}
+		return col + " IS NULL"
+	case opLike:
+		s, ok := v.(string)
+		if !ok {
+			w.invalid(c.name, op, "expects a string")
+			return ""
+		}
+		switch op {
+		case "$like":
+			return col + " LIKE " + w.str(c.name, op, s)
+		case "$ilike":
+			if w.dialect == nil {
+				return "LOWER(" + col + ") LIKE LOWER(" + w.str(c.name, op, s) + ")"
+			}
+			return w.dialect.ILike(col, w.str(c.name, op, s))
+		case "$startsWith":
+			s = w.escapeLike(s) + "%"
+		case "$endsWith":
+			s = "%" + w.escapeLike(s)
+		}
+		return col + " LIKE " + w.str(c.name, op, s) + " ESCAPE '" + LikeEscape + "'"
+	case opRegexp:
+		s, ok := v.(string)
+		if !ok {
+			w.invalid(c.name, op, "expects a string")
+			return ""
+		}
+		if strings.Trim(cond.Options, "i") != "" {
+			w.invalid(c.name, "$options", "only the i option is supported")
+			return ""
+		}
+		rd, ok := w.dialect.(RegexpDialect)
+		if w.dialect == nil {
+			rd, ok = MySQL.(RegexpDialect)
+		}
+		if !ok {
+			w.invalid(c.name, op, "not supported by the dialect")
+			return ""
+		}
+		return rd.Regexp(col, w.str(c.name, op, s), cond.Options != "")
+	case opBetween:
+		bounds, ok := v.([]interface{})
+		if !ok || len(bounds) != 2 {
+			w.invalid(c.name, op, "expects an array of 2 values")
+			return ""
+		}
+		lo, hi := w.value(c, op, bounds[0], true), w.value(c, op, bounds[1], true)
+		if lo != "" && hi != "" {
+			return col + " BETWEEN " + lo + " AND " + hi
+		}
+	case opNot:
+		switch t := v.(type) {
+		case []*Cond:
+			if cond := w.operators(c, t); cond != "" {
+				return "NOT (" + cond + ")"
+			}
+			return ""
+		case string:
+			if len(t) >= 2 && strings.HasPrefix(t, "/") && strings.HasSuffix(t, "/") {
+				return "NOT (" + w.like(c, t[1:len(t)-1]) + ")"
+			}
+		}
+		w.invalid(c.name, op, "expects an object or a /pattern/")
+	case opArray:
+		ad, ok := w.dialect.(ArrayDialect)
+		if !ok {
+			w.invalid(c.name, op, "not supported by the dialect")
+			return ""
+		}
+		return w.array(ad, c, col, op, v)
+	}
+	return ""
+}
+
+// values writes the elements of list, ok is false if one of them is invalid.
+func (w *sqlWriter) values(c *column, op string, list []interface{}) (values []string, ok bool) {
+	values = make([]string, 0, len(list))
+	for _, v := range list {
+		if val := w.value(c, op, v, false); val != "" {
+			values = append(values, val)
+		}
+	}
+	return values, len(values) == len(list)
 }
 
-func appendWhere(src string,condition string, elem string) string {
-	if src != "" {
-		return src + " " + condition +  " " + elem
+// array returns the condition of $all, $size or $elemMatch on an array column.
+func (w *sqlWriter) array(ad ArrayDialect, c *column, col, op string, v interface{}) string {
+	switch op {
+	case "$all":
+		list, ok := v.([]interface{})
+		if !ok || len(list) == 0 {
+			w.invalid(c.name, op, "expects a non empty array")
+			return ""
+		}
+		if values, ok := w.values(c, op, list); ok {
+			return ad.ArrayContains(col, values)
+		}
+	case "$size":
+		n, ok := v.(float64)
+		if !ok || n < 0 || n != math.Trunc(n) {
+			w.invalid(c.name, op, "expects a positive integer")
+			return ""
+		}
+		return ad.ArrayLength(col) + " = " + w.value(&column{name: c.name}, op, int64(n), false)
+	case "$elemMatch":
+		ops, ok := v.([]*Cond)
+		if !ok {
+			w.invalid(c.name, op, "expects an object")
+			return ""
+		}
+		from, elem := ad.ArrayElements(col)
+		if cond := w.operators(&column{name: c.name, field: c.field, sql: elem}, ops); cond != "" {
+			return "EXISTS (SELECT 1 FROM " + from + " WHERE " + cond + ")"
+		}
 	}
-	return elem
+	return ""
 }
 
 func toSqlString(v interface{}, timeLocal *time.Location) (result string) {
 	switch t := v.(type) {
 	case int:
-		result = strconv.Itoa(v.(int))
+		result = strconv.Itoa(t)
 	case int64:
-		result = strconv.FormatInt(v.(int64), 10)
+		result = strconv.FormatInt(t, 10)
 	case float64:
-		result = strconv.FormatFloat(v.(float64), 'f', -1, 64)
+		result = strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		result = strings.ToUpper(strconv.FormatBool(t))
+	case time.Time:
+		result = "'" + t.Format("2006-01-02 15:04:05") + "'"
 	case string:
 		if timeLocal != nil {
-			if tm, err := time.Parse(time.RFC3339, v.(string)); err == nil {
+			if tm, err := time.Parse(time.RFC3339, t); err == nil {
 				result = "'" + tm.In(timeLocal).Format("2006-01-02 15:04:05") + "'"
 			} else {
-				result = "'" + v.(string) + "'"
+				result = MySQL.QuoteString(t)
 			}
 		} else {
-			result = "'" + v.(string) + "'"
+			result = MySQL.QuoteString(t)
 		}
 	default:
 		log.Println("toSqlString unkown type ", t)
 	}
 	return
 }
+
+// quote returns s as a standard SQL string literal, doubling its single quotes.
+// MySQL also needs its backslashes escaped, see MySQL.QuoteString.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// escapeLike escapes the LIKE wildcards of s with LikeEscape.
+func escapeLike(s string) string {
+	return strings.NewReplacer(LikeEscape, LikeEscape+LikeEscape, "%", LikeEscape+"%", "_", LikeEscape+"_").Replace(s)
+}
+
+// escapeLike is escapeLike for the dialect, SQLServer also needs "[" escaped
+// as it starts a character class.
+func (w *sqlWriter) escapeLike(s string) string {
+	s = escapeLike(s)
+	if _, ok := w.dialect.(sqlServerDialect); ok {
+		s = strings.Replace(s, "[", LikeEscape+"[", -1)
+	}
+	return s
+}
+
+// isIdentifier reports whether s is a column name like "name" or "user.name".
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, part := range strings.Split(s, ".") {
+		if part == "" {
+			return false
+		}
+		for i, r := range part {
+			if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -82,4 +82,28 @@ func TestQ2Sql(t *testing.T) {
 
 
 
+}
+
+func TestQ2SqlArgs(t *testing.T) {
+	var local, _ = time.LoadLocation("Asia/Shanghai")
+	q := `{"name":"o'k","desc":"/50%_off!/","created_at":{"$gte":"2010-05-18T00:00:00+08:00"},"age":{"$in":[1,2]},"$or":{"a":1,"b":null}}`
+
+	where, args, err := Q2SqlArgs(q, local, nil, DollarPlaceholder)
+	expect := `name = $1 AND desc LIKE $2 ESCAPE '!' AND created_at >= $3 AND age IN ($4,$5) AND (a = $6 OR b IS NULL)`
+	if err != nil || where != expect {
+		t.Fatal("result is", where, "but expect", expect, err)
+	}
+	tm := time.Date(2010, 5, 18, 0, 0, 0, 0, local)
+	if len(args) != 6 || args[0] != "o'k" || args[1] != "%50!%!_off!!%" || !args[2].(time.Time).Equal(tm) || args[3] != int64(1) || args[5] != int64(1) {
+		t.Fatal("unexpected args", args)
+	}
+
+	if where := Q2Sql(`{"name":"o'k"}`, local, nil); where != `name = 'o''k'` {
+		t.Fatal("quotes are not escaped:", where)
+	}
+	for _, bad := range []string{`{"name":{"$foo":1}}`, `{"name; DROP TABLE x":1}`, `{"$xor":{"a":1}}`, `{"a":{"$in":1}}`, `{`} {
+		if _, _, err := Q2SqlArgs(bad, local, nil, nil); err == nil {
+			t.Fatal("expect an error for", bad)
+		}
+	}
 }
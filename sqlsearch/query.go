@@ -0,0 +1,117 @@
+package sqlsearch
+
+import (
+	"encoding/json"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Query is the parsed form of the list parameters, rendered by the backends:
+// SqlSearch for SQL, ToMongo, ToElastic and Eval.
+type Query struct {
+	// Filter is the q parameter, nil without one.
+	Filter *Group
+	Sort   []Sort
+	Select []Projection
+	Limit  int
+	Offset int
+}
+
+// Sort is a sort key, "-name" sorts by name in descending order.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Projection is a selected field, "$name" selects the distinct values of name.
+type Projection struct {
+	Field    string
+	Distinct bool
+}
+
+// MaxLimit is the default and the maximum number of rows of a query.
+const MaxLimit = 2000
+
+// Parse parses the q, sort, select, limit and page parameters of a list request:
+//
+//	?q={"age":{"$gte":18}}&sort=["-created_at"]&select=["name","age"]&limit=10&page=2
+//
+// The error is a ValidationErrors listing every malformed parameter.
+func Parse(values url.Values) (*Query, error) {
+	q, errs := parse(values)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return q, nil
+}
+
+// parse returns the valid parts of values with the errors of the others.
+func parse(values url.Values) (*Query, ValidationErrors) {
+	var errs ValidationErrors
+	invalid := func(param, msg string) {
+		errs = append(errs, &ValidationError{Param: param, Message: msg})
+	}
+	q := &Query{Limit: MaxLimit}
+	if lmt := values.Get("limit"); lmt != "" {
+		if lmtInt, err := strconv.Atoi(lmt); err != nil {
+			invalid("limit", "not a number")
+		} else if lmtInt <= 0 {
+			invalid("limit", "must be positive")
+		} else if lmtInt < MaxLimit {
+			q.Limit = lmtInt
+		}
+	}
+	if qSelect := values.Get("select"); qSelect != "" {
+		var searchSelect []string
+		if err := json.Unmarshal([]byte(qSelect), &searchSelect); err == nil {
+			for _, v := range searchSelect {
+				if len(v) < 2 || v[0] == '-' {
+					continue
+				}
+				if v[0] == '$' {
+					q.Select = append(q.Select, Projection{Field: v[1:], Distinct: true})
+				} else {
+					q.Select = append(q.Select, Projection{Field: v})
+				}
+			}
+		} else {
+			invalid("select", "invalid JSON: "+err.Error())
+		}
+	}
+	if qSort := values.Get("sort"); qSort != "" {
+		var searchSort []string
+		if err := json.Unmarshal([]byte(qSort), &searchSort); err == nil {
+			for _, v := range searchSort {
+				if strings.HasPrefix(v, "-") {
+					q.Sort = append(q.Sort, Sort{Field: v[1:], Desc: true})
+				} else {
+					q.Sort = append(q.Sort, Sort{Field: v})
+				}
+			}
+		} else {
+			invalid("sort", "invalid JSON: "+err.Error())
+		}
+	}
+	if page := values.Get("page"); page != "" {
+		if pageInt, err := strconv.Atoi(page); err != nil {
+			invalid("page", "not a number")
+		} else if pageInt < 0 {
+			invalid("page", "must not be negative")
+		} else if pageInt-1 > math.MaxInt/q.Limit {
+			invalid("page", "out of range")
+		} else if pageInt > 0 {
+			q.Offset = q.Limit * (pageInt - 1)
+		}
+	}
+	if qs := values.Get("q"); qs != "" {
+		g, err := parseQ(qs)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			q.Filter = g
+		}
+	}
+	return q, errs
+}
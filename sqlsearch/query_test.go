@@ -0,0 +1,43 @@
+package sqlsearch
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	q, err := Parse(url.Values{
+		"q":      {`{"age":{"$gte":18}}`},
+		"sort":   {`["-created_at","name"]`},
+		"select": {`["name","$city","-age"]`},
+		"limit":  {"10"},
+		"page":   {"3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Sort) != 2 || q.Sort[0] != (Sort{Field: "created_at", Desc: true}) || q.Sort[1] != (Sort{Field: "name"}) {
+		t.Fatal("unexpected sort", q.Sort)
+	}
+	if len(q.Select) != 2 || q.Select[1] != (Projection{Field: "city", Distinct: true}) {
+		t.Fatal("unexpected select", q.Select)
+	}
+	if q.Limit != 10 || q.Offset != 20 || len(q.Filter.Nodes) != 1 {
+		t.Fatal("unexpected query", q)
+	}
+
+	if q, _ := Parse(url.Values{"page": {"0"}}); q.Limit != MaxLimit || q.Offset != 0 || q.Filter != nil {
+		t.Fatal("unexpected default query", q)
+	}
+	_, err = Parse(url.Values{"limit": {"x"}, "sort": {"name"}, "q": {`{"a":`}})
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 3 || errs[1].Param != "sort" || errs[2].Param != "q" {
+		t.Fatal("unexpected errors", err)
+	}
+	if _, err := Parse(url.Values{"limit": {"3"}, "page": {"6148914691236517207"}}); err == nil {
+		t.Fatal("expect an error for an overflowing offset")
+	}
+	_, err = Parse(url.Values{"limit": {"-5"}, "page": {"-1"}})
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 2 || errs[0].Param != "limit" || errs[1].Param != "page" {
+		t.Fatal("unexpected errors", err)
+	}
+}
@@ -0,0 +1,210 @@
+package sqlsearch
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldType is the value type of a schema field, values of q are coerced to it.
+type FieldType int
+
+const (
+	// TypeAny keeps values as they are decoded from JSON.
+	TypeAny FieldType = iota
+	TypeString
+	TypeInt
+	TypeFloat
+	TypeBool
+	// TypeTime accepts RFC 3339 strings, dates like "2006-01-02" and unix seconds.
+	TypeTime
+	// TypeJSON is a JSON column, its properties are fields too, such as "attrs.color" of a field "attrs".
+	TypeJSON
+)
+
+func (t FieldType) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeBool:
+		return "bool"
+	case TypeTime:
+		return "time"
+	case TypeJSON:
+		return "json"
+	}
+	return "any"
+}
+
+// Field describes a field clients may use in the q, sort and select parameters.
+type Field struct {
+	Name string
+	// Column is the SQL expression of the field, the KeyAlias of Name by default.
+	Column string
+	Type   FieldType
+	// Operators lists the operators allowed in q, such as "$eq" or "$in", nil allows all of them.
+	// Plain values count as "$eq" and "/text/" patterns as "$like".
+	Operators  []string
+	Sortable   bool
+	Selectable bool
+}
+
+func (f *Field) allows(op string) bool {
+	if f.Operators == nil {
+		return true
+	}
+	for _, o := range f.Operators {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema is the whitelist of fields of a SqlSearch, other fields are rejected.
+type Schema struct {
+	fields map[string]*Field
+}
+
+// NewSchema returns a Schema of fields.
+//
+//	schema := sqlsearch.NewSchema(
+//		sqlsearch.Field{Name: "name", Type: sqlsearch.TypeString, Sortable: true, Selectable: true},
+//		sqlsearch.Field{Name: "created", Column: "created_at", Type: sqlsearch.TypeTime, Operators: []string{"$gte", "$lt"}, Sortable: true},
+//	)
+func NewSchema(fields ...Field) *Schema {
+	s := &Schema{fields: make(map[string]*Field, len(fields))}
+	for i := range fields {
+		f := fields[i]
+		s.fields[f.Name] = &f
+	}
+	return s
+}
+
+// Field returns the field called name.
+func (s *Schema) Field(name string) (*Field, bool) {
+	f, ok := s.fields[name]
+	return f, ok
+}
+
+// jsonField returns the TypeJSON field of the longest prefix of a dotted name and the path of the rest.
+func (s *Schema) jsonField(name string) (*Field, []string) {
+	parts := strings.Split(name, ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		if f, ok := s.fields[strings.Join(parts[:i], ".")]; ok && f.Type == TypeJSON {
+			for _, p := range parts[i:] {
+				if !isIdentifier(p) {
+					return nil, nil
+				}
+			}
+			return f, parts[i:]
+		}
+	}
+	return nil, nil
+}
+
+// ValidationError describes a rejected part of a query.
+type ValidationError struct {
+	// Param is the URL parameter: "q", "sort", "select", "limit" or "page".
+	Param    string
+	Field    string
+	Operator string
+	Message  string
+}
+
+func (e *ValidationError) Error() string {
+	s := "sqlsearch: " + e.Param
+	if e.Field != "" {
+		s += ": field " + strconv.Quote(e.Field)
+	}
+	if e.Operator != "" {
+		s += ": " + e.Operator
+	}
+	return s + ": " + e.Message
+}
+
+// ValidationErrors are all the errors found in a query.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// coerce converts a JSON value to typ, times are converted to loc when it is not nil.
+func coerce(typ FieldType, v interface{}, loc *time.Location) (interface{}, error) {
+	switch typ {
+	case TypeString:
+		switch t := v.(type) {
+		case string:
+			return t, nil
+		case float64:
+			return strconv.FormatFloat(t, 'f', -1, 64), nil
+		}
+	case TypeInt:
+		switch t := v.(type) {
+		case float64:
+			if t == math.Trunc(t) && math.Abs(t) < 1<<63 {
+				return int64(t), nil
+			}
+		case string:
+			if i, err := strconv.ParseInt(t, 10, 64); err == nil {
+				return i, nil
+			}
+		}
+	case TypeFloat:
+		switch t := v.(type) {
+		case float64:
+			return t, nil
+		case string:
+			if f, err := strconv.ParseFloat(t, 64); err == nil {
+				return f, nil
+			}
+		}
+	case TypeBool:
+		switch t := v.(type) {
+		case bool:
+			return t, nil
+		case string:
+			if b, err := strconv.ParseBool(t); err == nil {
+				return b, nil
+			}
+		}
+	case TypeTime:
+		var tm time.Time
+		var err error
+		switch t := v.(type) {
+		case float64:
+			sec, frac := math.Modf(t)
+			tm = time.Unix(int64(sec), int64(frac*1e9))
+		case string:
+			if tm, err = time.Parse(time.RFC3339, t); err != nil {
+				l := loc
+				if l == nil {
+					l = time.UTC
+				}
+				tm, err = time.ParseInLocation("2006-01-02", t, l)
+			}
+		default:
+			err = fmt.Errorf("unsupported type")
+		}
+		if err == nil {
+			if loc != nil {
+				tm = tm.In(loc)
+			}
+			return tm, nil
+		}
+	default:
+		return v, nil
+	}
+	return nil, fmt.Errorf("%v is not a valid %s", v, typ)
+}
@@ -0,0 +1,49 @@
+package sqlsearch
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSchema(t *testing.T) {
+	schema := NewSchema(
+		Field{Name: "name", Type: TypeString, Sortable: true, Selectable: true},
+		Field{Name: "age", Column: "u.age", Type: TypeInt, Operators: []string{"$gt", "$in"}, Sortable: true},
+		Field{Name: "created", Column: "created_at", Type: TypeTime},
+		Field{Name: "active", Type: TypeBool},
+	)
+	uriQuery := url.Values{
+		"q":      {`{"name":"bob","age":{"$gt":"18","$in":[1,2]},"created":"2018-09-18","active":"true"}`},
+		"sort":   {`["-age"]`},
+		"select": {`["name"]`},
+	}
+	q := New(uriQuery, time.UTC, nil)
+	q.Schema = schema
+	sq, args, err := q.ToSqlArgs()
+	if err != nil || sq.Where != "name = ? AND u.age > ? AND u.age IN (?,?) AND created_at = ? AND active = ?" ||
+		sq.Order != "u.age DESC" || sq.Select != "name" {
+		t.Fatal("unexpected query", sq, err)
+	}
+	if args[1] != int64(18) || args[3] != int64(2) || !args[4].(time.Time).Equal(time.Date(2018, 9, 18, 0, 0, 0, 0, time.UTC)) || args[5] != true {
+		t.Fatal("values are not coerced", args)
+	}
+	if q.ToSql().Where != "name = 'bob' AND u.age > 18 AND u.age IN (1,2) AND created_at = '2018-09-18 00:00:00' AND active = TRUE" {
+		t.Fatal("unexpected literal query", q.ToSql().Where)
+	}
+
+	uriQuery.Set("q", `{"password":"x","age":{"$lt":3,"$gt":"old"},"name":"/b/"}`)
+	uriQuery.Set("sort", `["created"]`)
+	uriQuery.Set("select", `["age"]`)
+	err = q.Validate()
+	errs, ok := err.(ValidationErrors)
+	if !ok || len(errs) != 5 {
+		t.Fatal("unexpected errors", err)
+	}
+	if e := errs[2]; e.Param != "q" || e.Field != "password" || e.Error() != `sqlsearch: q: field "password": unknown field` {
+		t.Fatal("unexpected error", e)
+	}
+	if sq := q.ToSql(); sq.Where != "name LIKE '%b%'" || sq.Order != "" || sq.Select != "" {
+		t.Fatal("invalid clauses are not dropped", sq)
+	}
+}
@@ -2,33 +2,41 @@ package sqlsearch
 
 import (
 	"net/url"
-	"strconv"
-	"encoding/json"
 	"strings"
 	"time"
 )
 
-
-type SqlQuery  struct {
-	Where string
+type SqlQuery struct {
+	Where  string
 	Select string
 	Order  string
 	Offset int
-	Limit int
+	Limit  int
+	// Paging is the LIMIT OFFSET or OFFSET FETCH clause following Order, it is only set with a Dialect.
+	Paging string
 }
 
-
 type SqlSearch struct {
 	UrlValues    url.Values
 	TimeLocation *time.Location
-	KeyAlias   func(key string)string
+	KeyAlias     func(key string) string
+	// Placeholder is the bind parameter style of ToSqlArgs, QuestionPlaceholder by default.
+	Placeholder Placeholder
+	// Schema restricts the fields of q, sort and select, and the operators of q, nil allows any field.
+	Schema *Schema
+	// Dialect selects the SQL syntax, nil keeps the MySQL like output without identifier quoting.
+	Dialect Dialect
 }
 
-func New(query url.Values, local *time.Location, keyAlias func(key string)string) *SqlSearch {
+// New returns a SqlSearch of query, an optional dialect such as Postgres selects the SQL syntax.
+func New(query url.Values, local *time.Location, keyAlias func(key string) string, dialect ...Dialect) *SqlSearch {
 	ret := SqlSearch{
-		UrlValues:query,
+		UrlValues:    query,
 		TimeLocation: local,
 	}
+	if len(dialect) > 0 {
+		ret.Dialect = dialect[0]
+	}
 	if keyAlias == nil {
 		ret.KeyAlias = func(key string) string {
 			return key
@@ -39,61 +47,82 @@ func New(query url.Values, local *time.Location, keyAlias func(key string)string
 	return &ret
 }
 
+// ToSql returns the clauses with the values written as literals, dropping the invalid parameters.
+// It is not safe for untrusted input: without a Dialect, field names of q, sort and select
+// are copied into the SQL as they are. Use ToSqlArgs for request parameters.
 func (this *SqlSearch) ToSql() *SqlQuery {
-	sq := SqlQuery{Limit: 2000,}
-	if lmt := this.UrlValues.Get("limit"); lmt != "" {
-		if lmtInt, err := strconv.Atoi(lmt); err == nil && lmtInt < 2000 {
-			sq.Limit = lmtInt
-		}
+	return this.toSql(this.writer(nil))
+}
+
+// ToSqlArgs is like ToSql but the WHERE clause has placeholders for the returned arguments,
+// see Q2SqlArgs. Parameters rejected by the Schema or malformed are returned as ValidationErrors
+// instead of being dropped.
+//
+//	sq, args, err := sqlsearch.New(r.URL.Query(), time.Local, nil).ToSqlArgs()
+//	rows, err := db.Query("SELECT * FROM users WHERE "+sq.Where+" LIMIT ? OFFSET ?", append(args, sq.Limit, sq.Offset)...)
+func (this *SqlSearch) ToSqlArgs() (*SqlQuery, []interface{}, error) {
+	placeholder := this.Placeholder
+	if placeholder == nil && this.Dialect != nil {
+		placeholder = this.Dialect.Placeholder
+	} else if placeholder == nil {
+		placeholder = QuestionPlaceholder
 	}
-	if qSelect := this.UrlValues.Get("select"); qSelect != "" {
-		var searchSelect []string
-		if err := json.Unmarshal([]byte(qSelect), &searchSelect); err == nil {
-			selectStr := ""
-			for i, v := range searchSelect {
-				if i > 0 {
-					selectStr += ","
-				}
-				if len(v) < 2 {
-					continue
-				}
-				switch  v[:1] {
-				case "-":
-					continue
-				case "$":
-					selectStr += ("DISTINCT(" + this.KeyAlias(v[1:]) + ")")
-				default:
-					selectStr += this.KeyAlias(v)
-				}
-			}
-			sq.Select = selectStr
-		}
+	w := this.writer(placeholder)
+	sq := this.toSql(w)
+	if err := w.err(); err != nil {
+		return nil, nil, err
 	}
-	if qSort := this.UrlValues.Get("sort"); qSort != "" {
-		var searchSort []string
-		if err := json.Unmarshal([]byte(qSort), &searchSort); err == nil {
-			sort := ""
-			for i, v := range searchSort {
-				if i > 0 {
-					sort += ","
-				}
-				if strings.HasPrefix(v, "-") {
-					sort += this.KeyAlias(v[1:]) + " DESC"
-				} else {
-					sort += this.KeyAlias(v)
-				}
-			}
-			sq.Order = sort
+	return sq, w.args, nil
+}
+
+// Validate returns the ValidationErrors of the query parameters, ToSql drops the invalid parts.
+func (this *SqlSearch) Validate() error {
+	_, _, err := this.ToSqlArgs()
+	return err
+}
+
+func (this *SqlSearch) writer(placeholder Placeholder) *sqlWriter {
+	w := newSqlWriter(this.TimeLocation, this.KeyAlias, placeholder)
+	w.schema = this.Schema
+	w.dialect = this.Dialect
+	return w
+}
+
+// Query parses the parameters of this, see Parse.
+func (this *SqlSearch) Query() (*Query, error) {
+	return Parse(this.UrlValues)
+}
+
+func (this *SqlSearch) toSql(w *sqlWriter) *SqlQuery {
+	query, errs := parse(this.UrlValues)
+	w.errs = append(w.errs, errs...)
+	sq := SqlQuery{Limit: query.Limit, Offset: query.Offset}
+	w.param = "select"
+	var selects []string
+	for _, p := range query.Select {
+		if col, ok := w.column(p.Field, "select"); ok && p.Distinct {
+			selects = append(selects, "DISTINCT("+col+")")
+		} else if ok {
+			selects = append(selects, col)
 		}
 	}
-
-	if page := this.UrlValues.Get("page"); page != "" {
-		if pageInt, err := strconv.Atoi(page);err == nil {
-			sq.Offset = sq.Limit * (pageInt - 1)
+	sq.Select = strings.Join(selects, ",")
+	w.param = "sort"
+	var sorts []string
+	for _, s := range query.Sort {
+		if col, ok := w.column(s.Field, "sort"); ok && s.Desc {
+			sorts = append(sorts, col+" DESC")
+		} else if ok {
+			sorts = append(sorts, col)
 		}
 	}
-	if q := this.UrlValues.Get("q"); q != "" {
-		sq.Where = Q2Sql(q, this.TimeLocation, this.KeyAlias)
+	sq.Order = strings.Join(sorts, ",")
+	if query.Filter != nil {
+		w.param = "q"
+		sq.Where, _ = w.group(query.Filter)
+	}
+	if w.dialect != nil {
+		sq.Paging = w.dialect.LimitOffset(sq.Limit, sq.Offset, sq.Order != "")
 	}
 	return &sq
-}
\ No newline at end of file
+}
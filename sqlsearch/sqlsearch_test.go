@@ -34,3 +34,23 @@ func TestUrlQuery2Sql(t *testing.T) {
 	}
 
 }
+
+func TestUrlQuery2SqlArgs(t *testing.T) {
+	uriQuery := url.Values{
+		"q":      {`{"name":"李南希","age":{"$gt":18}}`},
+		"sort":   {`["-ctime"]`},
+		"select": {`["name","$city"]`},
+	}
+	q := New(uriQuery, time.UTC, nil)
+	q.Placeholder = AtPlaceholder
+	sqlQuery, args, err := q.ToSqlArgs()
+	if err != nil || sqlQuery.Where != "name = @p1 AND age > @p2" || sqlQuery.Order != "ctime DESC" ||
+		sqlQuery.Select != "name,DISTINCT(city)" || len(args) != 2 || args[0] != "李南希" || args[1] != int64(18) {
+		t.Fatal("unexpected query", sqlQuery, args, err)
+	}
+
+	uriQuery.Set("sort", `["ctime; DROP TABLE users"]`)
+	if _, _, err := q.ToSqlArgs(); err == nil {
+		t.Fatal("expect an error for an invalid sort key")
+	}
+}